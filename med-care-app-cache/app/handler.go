@@ -1,4 +1,4 @@
-package main
+package app
 
 import (
 	"context"
@@ -22,7 +22,16 @@ func NewHandler(repo DashboardRepository, obs metrics.Obs) *Handler {
 }
 
 func (h *Handler) UserDashboard(ctx context.Context, userID int64, publishedFrom *time.Time, limit int) (*model.FeedResponse, error) {
-	mainSpan := h.obs.StartSpan("UserDashboard")
+	page := "1"
+	if publishedFrom != nil {
+		page = "2+"
+	}
+
+	ctx, mainSpan := h.obs.Start(ctx, "UserDashboard", metrics.String("page", page))
+	mainSpan.SetArgs(metrics.Int("user_id", int(userID)), metrics.Int("limit", limit))
+	if publishedFrom != nil {
+		mainSpan.SetArgs(metrics.String("published_from", publishedFrom.Format(time.RFC3339Nano)))
+	}
 	var err error
 	defer func() {
 		mainSpan.Done(err)
@@ -40,7 +49,7 @@ func (h *Handler) UserDashboard(ctx context.Context, userID int64, publishedFrom
 
 	// Get articles
 	g.Go(func() error {
-		op := h.obs.StartSpan("GetArticleFeed")
+		ctx, op := h.obs.Start(ctx, "GetArticleFeed")
 		articles, err := h.repo.GetArticleFeed(ctx, userID, limit, publishedFrom)
 		op.Done(err)
 		if err != nil {
@@ -52,7 +61,7 @@ func (h *Handler) UserDashboard(ctx context.Context, userID int64, publishedFrom
 
 	// Get care plan steps
 	g.Go(func() error {
-		op := h.obs.StartSpan("GetLatestCarePlanSteps")
+		ctx, op := h.obs.Start(ctx, "GetLatestCarePlanSteps")
 		steps, err := h.repo.GetLatestCarePlanSteps(ctx, userID)
 		op.Done(err)
 		if err != nil {
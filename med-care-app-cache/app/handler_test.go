@@ -1,7 +1,85 @@
-package main
+package app
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rusinikita/system-design-trainer/med-care-app-cache/model"
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+	"github.com/rusinikita/system-design-trainer/tooling/metrics/metricstest"
+)
+
+type fakeRepo struct {
+	feedErr error
+	// stepsBlock makes GetLatestCarePlanSteps wait for context cancellation
+	stepsBlock bool
+}
+
+func (r *fakeRepo) GetArticleFeed(ctx context.Context, userID int64, limit int, publishedFrom *time.Time) ([]model.Article, error) {
+	if r.feedErr != nil {
+		return nil, r.feedErr
+	}
+
+	return []model.Article{{ID: 1, Title: "Article 1"}}, nil
+}
+
+func (r *fakeRepo) GetLatestCarePlanSteps(ctx context.Context, userID int64) ([]model.CarePlanStep, error) {
+	if r.stepsBlock {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	return []model.CarePlanStep{{ID: 1, Title: "Step 1"}}, nil
+}
 
 func TestUserDashboard(t *testing.T) {
+	obs := metricstest.NewRecorder()
+	h := NewHandler(&fakeRepo{}, obs)
+
+	resp, err := h.UserDashboard(context.Background(), 1, nil, 10)
+	require.NoError(t, err)
+	assert.Len(t, resp.Articles, 1)
+	assert.Len(t, resp.Steps, 1)
+
+	obs.AssertOK(t, "UserDashboard")
+	obs.AssertOK(t, "GetArticleFeed")
+	obs.AssertOK(t, "GetLatestCarePlanSteps")
+	obs.AssertAttr(t, "UserDashboard", metrics.String("page", "1"))
+	obs.AssertArg(t, "UserDashboard", metrics.Int("user_id", 1))
+	obs.AssertArg(t, "UserDashboard", metrics.Int("limit", 10))
+	obs.AssertCovers(t, "UserDashboard", "GetArticleFeed", "GetLatestCarePlanSteps")
+	obs.AssertAllDone(t)
+}
+
+func TestUserDashboardNextPage(t *testing.T) {
+	obs := metricstest.NewRecorder()
+	h := NewHandler(&fakeRepo{}, obs)
+
+	from := time.Now()
+	_, err := h.UserDashboard(context.Background(), 1, &from, 10)
+	require.NoError(t, err)
+
+	obs.AssertAttr(t, "UserDashboard", metrics.String("page", "2+"))
+	obs.AssertArg(t, "UserDashboard", metrics.String("published_from", from.Format(time.RFC3339Nano)))
+}
+
+func TestUserDashboardError(t *testing.T) {
+	obs := metricstest.NewRecorder()
+	feedErr := errors.New("feed failed")
+	h := NewHandler(&fakeRepo{feedErr: feedErr, stepsBlock: true}, obs)
+
+	_, err := h.UserDashboard(context.Background(), 1, nil, 10)
+	require.ErrorIs(t, err, feedErr)
 
+	obs.AssertError(t, "GetArticleFeed", feedErr)
+	// the failed feed cancels the concurrent steps query
+	obs.AssertError(t, "GetLatestCarePlanSteps", context.Canceled)
+	obs.AssertError(t, "UserDashboard", feedErr)
+	obs.AssertCovers(t, "UserDashboard", "GetArticleFeed", "GetLatestCarePlanSteps")
+	obs.AssertAllDone(t)
 }
@@ -10,6 +10,8 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/rusinikita/system-design-trainer/tooling/clock"
 )
 
 func TestGetArticleFeed(t *testing.T) {
@@ -59,7 +61,7 @@ func TestGetArticleFeed(t *testing.T) {
 	}
 
 	// 2. Create repository
-	repo := NewDashboardRepository(db)
+	repo := NewDashboardRepository(db, clock.Wall{})
 
 	// 3. Call method
 	articles, err := repo.GetArticleFeed(ctx, 1, 10, nil)
@@ -130,7 +132,7 @@ func TestGetLatestCarePlanSteps(t *testing.T) {
 	}
 
 	// 2. Create repository
-	repo := NewDashboardRepository(db)
+	repo := NewDashboardRepository(db, clock.Wall{})
 
 	// 3. Call method
 	steps, err := repo.GetLatestCarePlanSteps(ctx, 1)
@@ -156,4 +158,13 @@ func TestGetLatestCarePlanSteps(t *testing.T) {
 	// Verify order by due date
 	assert.True(t, steps[0].DueDate.Before(steps[1].DueDate))
 	assert.True(t, steps[1].DueDate.Before(steps[2].DueDate))
+
+	// Three days later by the simulated clock pending steps are past due
+	later := NewDashboardRepository(db, clock.NewSimulated(now.Add(72*time.Hour), time.Hour))
+	steps, err = later.GetLatestCarePlanSteps(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, steps, 3)
+	for _, s := range steps {
+		assert.Equal(t, "overdue", s.Status)
+	}
 }
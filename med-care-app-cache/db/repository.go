@@ -4,22 +4,34 @@ import (
 	"context"
 	"database/sql"
 	"github.com/rusinikita/system-design-trainer/med-care-app-cache/model"
+	"github.com/rusinikita/system-design-trainer/tooling/clock"
 	"time"
 )
 
+// Querier is satisfied by *sql.DB, *sql.Tx and the instrumented tooling/db.DB
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
 type DashboardRepository struct {
-	db *sql.DB
+	db    Querier
+	clock clock.Clock
 }
 
-func NewDashboardRepository(db *sql.DB) *DashboardRepository {
+// NewDashboardRepository queries with the clock time instead of SQL NOW(), so simulated runs see their own "now"
+func NewDashboardRepository(db Querier, clock clock.Clock) *DashboardRepository {
 	return &DashboardRepository{
-		db: db,
+		db:    db,
+		clock: clock,
 	}
 }
 
-// GetArticleFeed returns personalized articles based on user segments with pagination
+// GetArticleFeed returns personalized articles based on user segments with pagination, articles published later than now are hidden
 func (r *DashboardRepository) GetArticleFeed(ctx context.Context, userID int64, limit int, publishedFrom *time.Time) ([]model.Article, error) {
 	query := `
+		-- name: GetArticleFeed
 		WITH user_segments AS (
 			SELECT segment_id, weight
 			FROM user_segments
@@ -43,12 +55,12 @@ func (r *DashboardRepository) GetArticleFeed(ctx context.Context, userID int64,
 		JOIN article_segments ags ON a.id = ags.article_id
 		JOIN user_segments us ON ags.segment_id = us.segment_id
 		LEFT JOIN read_articles ra ON a.id = ra.article_id AND ra.user_id = $1
-		WHERE ($3::timestamp IS NULL OR a.published_at < $3)
+		WHERE ($3::timestamp IS NULL OR a.published_at < $3) AND a.published_at <= $4
 		ORDER BY a.id, relevance DESC, a.published_at DESC
 		LIMIT $2
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, userID, limit, publishedFrom)
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, publishedFrom, r.clock.Now())
 	if err != nil {
 		return nil, err
 	}
@@ -81,9 +93,10 @@ func (r *DashboardRepository) GetArticleFeed(ctx context.Context, userID int64,
 	return articles, nil
 }
 
-// GetLatestCarePlanSteps returns the 3 most recent care plan steps for a user
+// GetLatestCarePlanSteps returns the 3 most recent care plan steps for a user, pending steps past due are overdue
 func (r *DashboardRepository) GetLatestCarePlanSteps(ctx context.Context, userID int64) ([]model.CarePlanStep, error) {
 	query := `
+		-- name: GetLatestCarePlanSteps
 		WITH user_care_plans AS (
 			SELECT id
 			FROM care_plans
@@ -94,7 +107,10 @@ func (r *DashboardRepository) GetLatestCarePlanSteps(ctx context.Context, userID
 			cps.type,
 			cps.title,
 			cps.description,
-			cps.status,
+			CASE
+				WHEN cps.status = 'pending' AND cps.due_date < $2 THEN 'overdue'
+				ELSE cps.status
+			END AS status,
 			cps.due_date,
 			cps.completed_at,
 			cps.metadata,
@@ -104,7 +120,7 @@ func (r *DashboardRepository) GetLatestCarePlanSteps(ctx context.Context, userID
 		WHERE cps.status != 'completed'
 		ORDER BY 
 			CASE 
-				WHEN cps.status = 'overdue' THEN 1
+				WHEN cps.status = 'overdue' OR cps.due_date < $2 THEN 1
 				WHEN cps.status = 'pending' THEN 2
 				ELSE 3
 			END,
@@ -112,7 +128,7 @@ func (r *DashboardRepository) GetLatestCarePlanSteps(ctx context.Context, userID
 		LIMIT 3
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, userID, r.clock.Now())
 	if err != nil {
 		return nil, err
 	}
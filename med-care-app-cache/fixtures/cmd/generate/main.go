@@ -1,9 +1,13 @@
+// Command generate recreates the schema and fills it with fixture data dated around the epoch
+//
+//	go run ./med-care-app-cache/fixtures/cmd/generate -epoch 2025-01-01
 package main
 
 import (
 	"context"
-	_ "embed"
+	"flag"
 	"fmt"
+	"github.com/rusinikita/system-design-trainer/med-care-app-cache/fixtures"
 	dbTool "github.com/rusinikita/system-design-trainer/tooling/db"
 	"log"
 	"math/rand"
@@ -13,9 +17,6 @@ import (
 	_ "github.com/lib/pq"
 )
 
-//go:embed schema.sql
-var schemaSQL string
-
 const (
 	// Input parameters
 	usersCount        = 1_000_000
@@ -23,8 +24,6 @@ const (
 	segmentTypesCount = 50 // Total number of possible segments
 
 	// Ranges
-	minUserSegments    = 2
-	maxUserSegments    = 7
 	minArticleSegments = 1
 	maxArticleSegments = 5
 	minSteps           = 10
@@ -35,12 +34,21 @@ const (
 )
 
 func main() {
+	epoch := flag.String("epoch", "", "date data is generated around, YYYY-MM-DD, empty is today")
+	flag.Parse()
+
+	now, err := fixtures.ParseEpoch(*epoch)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	db := dbTool.Conn()
 	defer db.Close()
 
 	ctx := context.Background()
+	props := fixtures.DefaultFixtureProperties()
 
-	_, err := db.Exec(schemaSQL)
+	_, err = db.Exec(fixtures.SchemaSQL)
 	if err != nil {
 		log.Fatalf("Failed to load schema: %v", err)
 	}
@@ -69,17 +77,18 @@ func main() {
 
 	for i := 0; i < segmentTypesCount; i++ {
 		segmentTypes[i] = fmt.Sprintf("medical_condition_%d", i)
-		values[i] = fmt.Sprintf("($%d, $%d, $%d)", i*3+1, i*3+2, i*3+3)
+		values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d)", i*4+1, i*4+2, i*4+3, i*4+4)
 		args = append(args,
 			i,
 			segmentTypes[i],
 			fmt.Sprintf("Description for %s", segmentTypes[i]),
+			now,
 		)
 	}
 
 	// Insert segment types
 	log.Println("Inserting segment types...")
-	query := fmt.Sprintf("INSERT INTO segment_types (id, name, description) VALUES %s",
+	query := fmt.Sprintf("INSERT INTO segment_types (id, name, description, created_at) VALUES %s",
 		strings.Join(values, ","))
 	if _, err := db.ExecContext(ctx, query, args...); err != nil {
 		log.Fatalf("Failed to insert segment types: %v", err)
@@ -89,7 +98,7 @@ func main() {
 	log.Println("Inserting articles and segments...")
 	for i := 0; i < articlesCount; i += batchSize {
 		// Insert articles batch
-		articleBatch := make([]interface{}, 0, batchSize*6)
+		articleBatch := make([]interface{}, 0, batchSize*7)
 		articleValueStrings := make([]string, 0, batchSize)
 		articleIDs := make([]int64, batchSize)
 
@@ -101,28 +110,31 @@ func main() {
 		for j := i; j < end; j++ {
 			articleID := int64(j)
 			articleIDs[j-i] = articleID
-			valueIdx := (j - i) * 6
+			valueIdx := (j - i) * 7
 
 			articleValueStrings = append(articleValueStrings,
-				fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)",
-					valueIdx+1, valueIdx+2, valueIdx+3, valueIdx+4, valueIdx+5, valueIdx+6))
+				fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+					valueIdx+1, valueIdx+2, valueIdx+3, valueIdx+4, valueIdx+5, valueIdx+6, valueIdx+7))
 
 			_type := "scientific"
 			if rand.Intn(2) == 0 {
 				_type = "news"
 			}
 
+			publishedAt := now.Add(-time.Duration(rand.Intn(365)) * 24 * time.Hour)
+
 			articleBatch = append(articleBatch,
 				articleID,
 				fmt.Sprintf("Article Title %d", j),
 				fmt.Sprintf("Content for article %d", j),
 				"Source",
 				_type,
-				time.Now().Add(-time.Duration(rand.Intn(365))*24*time.Hour),
+				publishedAt,
+				publishedAt,
 			)
 		}
 
-		query := fmt.Sprintf("INSERT INTO articles (id, title, content, source, type, published_at) VALUES %s",
+		query := fmt.Sprintf("INSERT INTO articles (id, title, content, source, type, published_at, created_at) VALUES %s",
 			strings.Join(articleValueStrings, ","))
 		if _, err := db.ExecContext(ctx, query, articleBatch...); err != nil {
 			log.Fatalf("Failed to insert articles: %v", err)
@@ -133,23 +145,24 @@ func main() {
 			segmentCount := minArticleSegments + rand.Intn(maxArticleSegments-minArticleSegments+1)
 			segments := rand.Perm(len(segmentTypes))[:segmentCount]
 
-			segmentBatch := make([]interface{}, 0, segmentCount*3)
+			segmentBatch := make([]interface{}, 0, segmentCount*4)
 			segmentValueStrings := make([]string, 0, segmentCount)
 
 			for j, segIdx := range segments {
-				valueIdx := j * 3
+				valueIdx := j * 4
 				segmentValueStrings = append(segmentValueStrings,
-					fmt.Sprintf("($%d, $%d, $%d)",
-						valueIdx+1, valueIdx+2, valueIdx+3))
+					fmt.Sprintf("($%d, $%d, $%d, $%d)",
+						valueIdx+1, valueIdx+2, valueIdx+3, valueIdx+4))
 
 				segmentBatch = append(segmentBatch,
 					articleID,
 					segIdx,
 					0.1+rand.Float64()*0.9, // relevance score between 0.1 and 1.0
+					now,
 				)
 			}
 
-			query := fmt.Sprintf("INSERT INTO article_segments (article_id, segment_id, relevance_score) VALUES %s",
+			query := fmt.Sprintf("INSERT INTO article_segments (article_id, segment_id, relevance_score, created_at) VALUES %s",
 				strings.Join(segmentValueStrings, ","))
 			if _, err := db.ExecContext(ctx, query, segmentBatch...); err != nil {
 				log.Fatalf("Failed to insert article segments: %v", err)
@@ -157,6 +170,11 @@ func main() {
 		}
 	}
 
+	// Articles have explicit ids, move the sequence past them, so published articles get the next ones
+	if _, err := db.ExecContext(ctx, "SELECT setval(pg_get_serial_sequence('articles', 'id'), MAX(id)) FROM articles"); err != nil {
+		log.Fatalf("Failed to update articles id sequence: %v", err)
+	}
+
 	// Insert users, their segments, care plans and steps
 	log.Println("Inserting users and related data...")
 	for i := 0; i < usersCount; i += batchSize {
@@ -166,22 +184,24 @@ func main() {
 		}
 
 		// Insert users batch
-		userBatch := make([]interface{}, 0, batchSize*2)
+		userBatch := make([]interface{}, 0, batchSize*4)
 		userValueStrings := make([]string, 0, batchSize)
 
 		for j := i; j < end; j++ {
-			valueIdx := (j - i) * 2
+			valueIdx := (j - i) * 4
 			userValueStrings = append(userValueStrings,
-				fmt.Sprintf("($%d, $%d)",
-					valueIdx+1, valueIdx+2))
+				fmt.Sprintf("($%d, $%d, $%d, $%d)",
+					valueIdx+1, valueIdx+2, valueIdx+3, valueIdx+4))
 
 			userBatch = append(userBatch,
 				j,                         // user id
 				fmt.Sprintf("User %d", j), // user name
+				now,                       // created at
+				now,                       // updated at
 			)
 		}
 
-		query := fmt.Sprintf("INSERT INTO users (id, name) VALUES %s",
+		query := fmt.Sprintf("INSERT INTO users (id, name, created_at, updated_at) VALUES %s",
 			strings.Join(userValueStrings, ","))
 		if _, err := db.ExecContext(ctx, query, userBatch...); err != nil {
 			log.Fatalf("Failed to insert users: %v", err)
@@ -191,27 +211,27 @@ func main() {
 		for j := i; j < end; j++ {
 			userID := j
 
-			// Insert user segments
-			segmentCount := minUserSegments + rand.Intn(maxUserSegments-minUserSegments+1)
-			segments := rand.Perm(len(segmentTypes))[:segmentCount]
+			// Insert user segments, load scenarios find segment users by the same properties
+			segments := props.UserSegments(int64(userID))
 
-			segmentBatch := make([]interface{}, 0, segmentCount*3)
-			segmentValueStrings := make([]string, 0, segmentCount)
+			segmentBatch := make([]interface{}, 0, len(segments)*4)
+			segmentValueStrings := make([]string, 0, len(segments))
 
 			for k, segIdx := range segments {
-				valueIdx := k * 3
+				valueIdx := k * 4
 				segmentValueStrings = append(segmentValueStrings,
-					fmt.Sprintf("($%d, $%d, $%d)",
-						valueIdx+1, valueIdx+2, valueIdx+3))
+					fmt.Sprintf("($%d, $%d, $%d, $%d)",
+						valueIdx+1, valueIdx+2, valueIdx+3, valueIdx+4))
 
 				segmentBatch = append(segmentBatch,
 					userID,
 					segIdx,
 					0.1+rand.Float64()*0.9, // weight between 0.1 and 1.0
+					now,
 				)
 			}
 
-			query := fmt.Sprintf("INSERT INTO user_segments (user_id, segment_id, weight) VALUES %s",
+			query := fmt.Sprintf("INSERT INTO user_segments (user_id, segment_id, weight, calculated_at) VALUES %s",
 				strings.Join(segmentValueStrings, ","))
 			if _, err := db.ExecContext(ctx, query, segmentBatch...); err != nil {
 				log.Fatalf("Failed to insert user segments: %v", err)
@@ -220,14 +240,15 @@ func main() {
 			// Create care plan
 			carePlanID := j
 			_, err := db.ExecContext(ctx,
-				`INSERT INTO care_plans (id, user_id, title, status, start_date, end_date)
-				VALUES ($1, $2, $3, $4, $5, $6)`,
+				`INSERT INTO care_plans (id, user_id, title, status, start_date, end_date, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)`,
 				carePlanID,
 				userID,
 				fmt.Sprintf("Care Plan for User %d", j),
 				"active",
-				time.Now(),
-				time.Now().AddDate(0, 6, 0),
+				now,
+				now.AddDate(0, 6, 0),
+				now,
 			)
 			if err != nil {
 				log.Fatalf("Failed to insert care plan: %v", err)
@@ -238,18 +259,18 @@ func main() {
 			stepTypes := []string{"appointment", "test", "upload", "checkup"}
 			stepStatuses := []string{"pending", "completed", "overdue"}
 
-			stepBatch := make([]interface{}, 0, stepCount*9)
+			stepBatch := make([]interface{}, 0, stepCount*10)
 			stepValueStrings := make([]string, 0, stepCount)
 
 			for k := 0; k < stepCount; k++ {
-				valueIdx := k * 9
+				valueIdx := k * 10
 				stepValueStrings = append(stepValueStrings,
-					fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+					fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
 						valueIdx+1, valueIdx+2, valueIdx+3, valueIdx+4, valueIdx+5,
-						valueIdx+6, valueIdx+7, valueIdx+8, valueIdx+9))
+						valueIdx+6, valueIdx+7, valueIdx+8, valueIdx+9, valueIdx+10))
 
 				stepType := stepTypes[rand.Intn(len(stepTypes))]
-				dueDate := time.Now().AddDate(0, 0, rand.Intn(180))
+				dueDate := now.AddDate(0, 0, rand.Intn(180))
 				var completedAt *time.Time
 				status := stepStatuses[rand.Intn(len(stepStatuses))]
 				if status == "completed" {
@@ -267,11 +288,12 @@ func main() {
 					dueDate,
 					completedAt,
 					k+1,
+					now,
 				)
 			}
 
 			query = fmt.Sprintf(`INSERT INTO care_plan_steps 
-				(id, care_plan_id, type, title, description, status, due_date, completed_at, order_number) 
+				(id, care_plan_id, type, title, description, status, due_date, completed_at, order_number, created_at) 
 				VALUES %s`, strings.Join(stepValueStrings, ","))
 			if _, err := db.ExecContext(ctx, query, stepBatch...); err != nil {
 				log.Fatalf("Failed to insert care plan steps: %v", err)
@@ -317,7 +339,7 @@ func main() {
 
 			// Randomly select articles
 			selectedArticles := make(map[int]bool)
-			readBatch := make([]interface{}, 0, readCount*4) // user_id, article_id, read_at, is_saved
+			readBatch := make([]interface{}, 0, readCount*5) // user_id, article_id, read_at, is_saved, created_at
 			readValueStrings := make([]string, 0, readCount)
 
 			for k := 0; k < readCount; k++ {
@@ -331,12 +353,12 @@ func main() {
 					}
 				}
 
-				valueIdx := k * 4
+				valueIdx := k * 5
 				readValueStrings = append(readValueStrings,
-					fmt.Sprintf("($%d, $%d, $%d, $%d)",
-						valueIdx+1, valueIdx+2, valueIdx+3, valueIdx+4))
+					fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)",
+						valueIdx+1, valueIdx+2, valueIdx+3, valueIdx+4, valueIdx+5))
 
-				readAt := time.Now().Add(-time.Duration(rand.Intn(90)) * 24 * time.Hour)
+				readAt := now.Add(-time.Duration(rand.Intn(90)) * 24 * time.Hour)
 				isSaved := rand.Float32() < 0.3 // 30% chance of saving the article
 
 				readBatch = append(readBatch,
@@ -344,10 +366,11 @@ func main() {
 					articleIDs[articleIndex],
 					readAt,
 					isSaved,
+					readAt,
 				)
 			}
 
-			query := fmt.Sprintf("INSERT INTO read_articles (user_id, article_id, read_at, is_saved) VALUES %s",
+			query := fmt.Sprintf("INSERT INTO read_articles (user_id, article_id, read_at, is_saved, created_at) VALUES %s",
 				strings.Join(readValueStrings, ","))
 			if _, err := db.ExecContext(ctx, query, readBatch...); err != nil {
 				log.Fatalf("Failed to insert read articles: %v", err)
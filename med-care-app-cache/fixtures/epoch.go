@@ -0,0 +1,22 @@
+package fixtures
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParseEpoch parses the date fixtures are generated around, YYYY-MM-DD. Load tests start their simulated clock
+// at the same date, so runs see the same timeline however long ago fixtures were generated.
+// Empty date is the current time.
+func ParseEpoch(date string) (time.Time, error) {
+	if date == "" {
+		return time.Now(), nil
+	}
+
+	t, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad epoch: %v", err)
+	}
+
+	return t, nil
+}
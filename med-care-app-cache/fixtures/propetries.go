@@ -1,4 +1,4 @@
-package main
+package fixtures
 
 import (
 	"iter"
@@ -16,6 +16,8 @@ type FixtureProperties struct {
 	MaxArticleSegments int
 	MinSteps           int
 	MaxSteps           int
+	// PushOpenShare is the part of segment users opening the app after a new article push
+	PushOpenShare float64
 }
 
 func DefaultFixtureProperties() FixtureProperties {
@@ -29,6 +31,7 @@ func DefaultFixtureProperties() FixtureProperties {
 		MaxArticleSegments: 5,
 		MinSteps:           5,
 		MaxSteps:           50,
+		PushOpenShare:      0.2,
 	}
 }
 
@@ -48,40 +51,44 @@ func (p *FixtureProperties) NextRandomUserID(r *rand.Rand) int64 {
 	return int64(r.IntN(p.UsersCount))
 }
 
-func (p *FixtureProperties) NextRandomSegmentUsers(r *rand.Rand) iter.Seq[int64] {
+// NextRandomSegmentUsers picks a random segment and returns users of it from a random 10-20% of all users
+func (p *FixtureProperties) NextRandomSegmentUsers(r *rand.Rand) (int64, iter.Seq[int64]) {
 	segmentID := p.nextRandomSegmentID(r)
 
-	return p.randomUsersForSegment(segmentID)
+	return segmentID, p.randomUsersForSegment(r, segmentID)
 }
 
+// UserSegments returns sorted segments of the user, the fixtures generator inserts exactly them
 func (p *FixtureProperties) UserSegments(id int64) []int64 {
-	segmentsCount := int(id)%(p.MaxUserSegments-p.MinUserSegments) + p.MinUserSegments
-
-	result := make([]int64, segmentsCount)
-	for i := 0; i < segmentsCount; i++ {
-		result[i] = id % int64(i*10)
+	segmentsCount := int(id)%(p.MaxUserSegments-p.MinUserSegments+1) + p.MinUserSegments
+	segmentsCount = min(segmentsCount, p.SegmentTypesCount)
+
+	result := make([]int64, 0, segmentsCount)
+	for i := uint64(0); len(result) < segmentsCount; i++ {
+		segmentID := int64(mix(uint64(id)<<8|i) % uint64(p.SegmentTypesCount))
+		if !slices.Contains(result, segmentID) {
+			result = append(result, segmentID)
+		}
 	}
 
-	return slices.Compact(result)
+	slices.Sort(result)
+
+	return result
 }
 
-func (p *FixtureProperties) randomUsersForSegment(segmentID int64) iter.Seq[int64] {
-	percent := rand.IntN(10) + 10
+func (p *FixtureProperties) randomUsersForSegment(r *rand.Rand, segmentID int64) iter.Seq[int64] {
+	percent := r.IntN(10) + 10
 	usersToScan := p.UsersCount / 100 * percent
-	scanStart := rand.IntN(p.UsersCount / 2)
+	scanStart := r.IntN(p.UsersCount - usersToScan + 1)
 
 	return func(yield func(int64) bool) {
-		for userID := scanStart; userID < usersToScan; userID++ {
-			segmentsCount := userID%(p.MaxUserSegments-p.MinUserSegments) + p.MinUserSegments
-
-			for i := 0; i < segmentsCount; i++ {
-				if segmentID != int64(userID%(i*10)) {
-					continue
-				}
+		for userID := int64(scanStart); userID < int64(scanStart+usersToScan); userID++ {
+			if !slices.Contains(p.UserSegments(userID), segmentID) {
+				continue
+			}
 
-				if !yield(int64(userID)) {
-					return
-				}
+			if !yield(userID) {
+				return
 			}
 		}
 	}
@@ -90,3 +97,14 @@ func (p *FixtureProperties) randomUsersForSegment(segmentID int64) iter.Seq[int6
 func (p *FixtureProperties) nextRandomSegmentID(r *rand.Rand) int64 {
 	return int64(r.IntN(p.SegmentTypesCount))
 }
+
+// mix is splitmix64 finalizer, it spreads consecutive ids evenly over segments
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
@@ -0,0 +1,50 @@
+package fixtures
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserSegments(t *testing.T) {
+	p := DefaultFixtureProperties()
+
+	used := map[int64]bool{}
+	for id := int64(0); id < 1000; id++ {
+		segments := p.UserSegments(id)
+
+		assert.GreaterOrEqual(t, len(segments), p.MinUserSegments)
+		assert.LessOrEqual(t, len(segments), p.MaxUserSegments)
+		assert.True(t, slices.IsSorted(segments))
+		assert.Len(t, slices.Compact(slices.Clone(segments)), len(segments))
+		assert.Equal(t, segments, p.UserSegments(id))
+
+		for _, s := range segments {
+			require.Less(t, s, int64(p.SegmentTypesCount))
+			used[s] = true
+		}
+	}
+
+	assert.Len(t, used, p.SegmentTypesCount)
+}
+
+func TestNextRandomSegmentUsers(t *testing.T) {
+	p := DefaultFixtureProperties()
+	p.UsersCount = 10_000
+
+	segmentID, users := p.NextRandomSegmentUsers(rand.New(rand.NewPCG(1, 1)))
+
+	var found []int64
+	for userID := range users {
+		require.Less(t, userID, int64(p.UsersCount))
+		assert.Contains(t, p.UserSegments(userID), segmentID)
+		found = append(found, userID)
+	}
+
+	// every user is in about 4.5 of 50 segments, 10-20% of users are scanned
+	assert.Greater(t, len(found), 50)
+	assert.Less(t, len(found), 250)
+}
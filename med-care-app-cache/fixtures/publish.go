@@ -0,0 +1,63 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/rusinikita/system-design-trainer/med-care-app-cache/db"
+)
+
+// PublishArticle inserts a new article tagged with one segment and returns its id.
+// The id comes from the table sequence, so concurrent publishes don't collide.
+func PublishArticle(ctx context.Context, q db.Querier, r *rand.Rand, segmentID int64, publishedAt time.Time) (int64, error) {
+	articleType := "scientific"
+	if r.IntN(2) == 0 {
+		articleType = "news"
+	}
+
+	query := `
+		-- name: PublishArticle
+		WITH article AS (
+			INSERT INTO articles (title, content, source, type, published_at, created_at)
+			VALUES ($1, $2, 'Source', $3, $4, $4)
+			RETURNING id
+		)
+		INSERT INTO article_segments (article_id, segment_id, relevance_score, created_at)
+		SELECT id, $5, $6, $4
+		FROM article
+		RETURNING article_id
+	`
+
+	var id int64
+	err := q.QueryRowContext(ctx, query,
+		fmt.Sprintf("News for segment %d", segmentID),
+		fmt.Sprintf("Content for segment %d news", segmentID),
+		articleType,
+		publishedAt,
+		segmentID,
+		0.5+r.Float64()*0.5, // pushed articles are highly relevant
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish article: %v", err)
+	}
+
+	return id, nil
+}
+
+// ReadArticle marks the article read by the user at readAt, repeated reads keep the first time
+func ReadArticle(ctx context.Context, q db.Querier, userID, articleID int64, readAt time.Time) error {
+	query := `
+		-- name: ReadArticle
+		INSERT INTO read_articles (user_id, article_id, read_at, created_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (user_id, article_id) DO NOTHING
+	`
+
+	if _, err := q.ExecContext(ctx, query, userID, articleID, readAt); err != nil {
+		return fmt.Errorf("failed to read article: %v", err)
+	}
+
+	return nil
+}
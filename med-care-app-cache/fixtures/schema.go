@@ -0,0 +1,8 @@
+package fixtures
+
+import _ "embed"
+
+// SchemaSQL creates the exercise tables
+//
+//go:embed schema.sql
+var SchemaSQL string
@@ -1,5 +1,217 @@
+// Command run_loadtest sends dashboard requests of random fixture users, draws live stats,
+// writes per-window metrics to log.csv and the slowest requests of every window to exemplars.jsonl.
+// Fill the database with fixtures/cmd/generate first.
+//
+//	go run ./med-care-app-cache -workers 50 -ramp-up 10s -duration 1m
+//	go run ./med-care-app-cache -web :8080 -workers 50 -duration 5m
+//	go run ./med-care-app-cache -mode open -profile step -rps 100 -peak 1000 -steps 9 -duration 5m
+//	go run ./med-care-app-cache -mode open -rps 200 -day 1m -epoch 2025-01-01 -push -push-spread 20s -duration 30m
+//
+// With -day, time runs on a simulated clock starting at -epoch, one simulated day lasts -day of wall time:
+// care plan steps become overdue and new articles pile up within a single run.
+// With -push, a new article is published into a random segment once per day and a part of the segment users
+// open the dashboard and read it on top of the background load.
 package main
 
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/rusinikita/system-design-trainer/med-care-app-cache/app"
+	"github.com/rusinikita/system-design-trainer/med-care-app-cache/fixtures"
+	"github.com/rusinikita/system-design-trainer/tooling/clock"
+	dbTool "github.com/rusinikita/system-design-trainer/tooling/db"
+	"github.com/rusinikita/system-design-trainer/tooling/loadgen"
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+	"golang.org/x/sync/errgroup"
+)
+
 func main() {
+	workers := flag.Int("workers", 20, "virtual users")
+	rampUp := flag.Duration("ramp-up", 10*time.Second, "period over which workers start")
+	duration := flag.Duration("duration", time.Minute, "run duration including ramp-up")
+	think := flag.Duration("think", 100*time.Millisecond, "pause of every worker between requests")
+	limit := flag.Int("limit", 20, "articles per dashboard page")
+	mode := flag.String("mode", "closed", "closed: fixed workers, open: fixed arrival rate")
+	profileName := flag.String("profile", "constant", "open mode rate profile: constant, ramp, step, spike or sine")
+	arrivals := flag.String("arrivals", "poisson", "open mode arrivals: poisson or uniform")
+	rps := flag.Float64("rps", 100, "open mode rate, the starting one for ramp, step and sine")
+	peak := flag.Float64("peak", 500, "open mode peak rate of ramp, step, spike and sine")
+	steps := flag.Int("steps", 5, "rate increases of the step profile")
+	maxInFlight := flag.Int("max-in-flight", 10_000, "open mode concurrent requests limit, 0 is unlimited")
+	day := flag.Duration("day", 0, "wall time of a simulated day, 0 is the real time")
+	epoch := flag.String("epoch", "", "simulated start date, YYYY-MM-DD, use the fixtures one, empty is today")
+	push := flag.Bool("push", false, "publish a new article once per day and send dashboard requests of its readers")
+	pushSpread := flag.Duration("push-spread", 10*time.Second, "period in which push openers arrive")
+	web := flag.String("web", "", "serve live charts on this address, e.g. :8080, instead of drawing in the terminal")
+	flag.Parse()
+
+	start, err := fixtures.ParseEpoch(*epoch)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var clk clock.Clock = clock.Wall{}
+	if *day > 0 {
+		clk = clock.NewSimulated(start, *day)
+	}
+
+	var load loadgen.Load
+	switch *mode {
+	case "closed":
+		load = loadgen.ClosedLoop{
+			Workers:   *workers,
+			RampUp:    *rampUp,
+			Duration:  *duration,
+			ThinkTime: *think,
+		}
+	case "open":
+		profile, err := newProfile(*profileName, *rps, *peak, *steps, clock.FromWall(clk, *duration))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		open := loadgen.OpenLoop{
+			Profile:     profile,
+			Clock:       clk,
+			Duration:    *duration,
+			MaxInFlight: *maxInFlight,
+		}
+		switch *arrivals {
+		case "poisson":
+			open.Arrivals = loadgen.Poisson
+		case "uniform":
+			open.Arrivals = loadgen.Uniform
+		default:
+			log.Fatalf("unknown arrivals %q", *arrivals)
+		}
+		load = open
+	default:
+		log.Fatalf("unknown mode %q", *mode)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	stats := dbTool.StatsConn()
+	defer stats.Close()
+
+	var live metrics.Sink = metrics.NewTerminalSink(os.Stdout, "loadgen.", "db.", "pg.", "sampler.")
+	if *web != "" {
+		webSink, err := metrics.NewWebSink(*web)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("live charts at http://%s", webSink.Addr())
+		live = webSink
+	}
+
+	exemplars, err := os.Create("exemplars.jsonl")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	obs, err := metrics.NewDefault(
+		metrics.WithSinks(live, metrics.NewExemplarSink(exemplars)),
+		metrics.WithSamplers(metrics.NewRuntimeSampler(), dbTool.NewPGStatsSampler(stats, dbTool.DashboardStatements)),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	conn, repo := dbTool.InstrumentedRepoConn(obs, clk)
+	defer conn.Close()
+
+	// the pool needs obs to be created, so its sampler is added before the pipeline starts
+	if err := obs.AddSamplers(metrics.NewDBStatsSampler("db", conn.DB)); err != nil {
+		log.Fatal(err)
+	}
+	obs.StartLogging(context.Background())
+
+	handler := app.NewHandler(repo, obs)
+	props := fixtures.DefaultFixtureProperties()
+
+	dashboard := func(userID int64) loadgen.Request {
+		return func(ctx context.Context, _ *rand.Rand) error {
+			_, err := handler.UserDashboard(ctx, userID, nil, *limit)
+			return err
+		}
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		return load.Run(ctx, obs, func(ctx context.Context, r *rand.Rand) error {
+			return dashboard(props.NextRandomUserID(r))(ctx, r)
+		})
+	})
+
+	if *push {
+		push := loadgen.Push{
+			Every:    24 * time.Hour,
+			Spread:   *pushSpread,
+			Duration: *duration,
+			Clock:    clk,
+			Burst: func(ctx context.Context, r *rand.Rand) ([]loadgen.Request, error) {
+				segmentID, users := props.NextRandomSegmentUsers(r)
+				articleID, err := fixtures.PublishArticle(ctx, conn, r, segmentID, clk.Now())
+				if err != nil {
+					return nil, err
+				}
+
+				var requests []loadgen.Request
+				for userID := range users {
+					if r.Float64() >= props.PushOpenShare {
+						continue
+					}
+
+					requests = append(requests, func(ctx context.Context, r *rand.Rand) error {
+						if err := dashboard(userID)(ctx, r); err != nil {
+							return err
+						}
+
+						return fixtures.ReadArticle(ctx, conn, userID, articleID, clk.Now())
+					})
+				}
+
+				return requests, nil
+			},
+		}
+
+		g.Go(func() error {
+			return push.Run(ctx, obs)
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := obs.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newProfile makes a profile of the whole run on the load clock, the spike takes a tenth of it in the middle
+func newProfile(name string, rps, peak float64, steps int, duration time.Duration) (loadgen.Profile, error) {
+	switch name {
+	case "constant":
+		return loadgen.Constant(rps), nil
+	case "ramp":
+		return loadgen.Ramp(rps, peak, duration), nil
+	case "step":
+		return loadgen.Step(rps, peak, steps, duration), nil
+	case "spike":
+		return loadgen.Spike(rps, peak, duration/2, duration/10), nil
+	case "sine":
+		return loadgen.Sine(rps, peak, duration/2), nil
+	}
 
+	return nil, fmt.Errorf("unknown profile %q", name)
 }
@@ -0,0 +1,83 @@
+// Package clock is the time source shared by fixtures, load generators and repository queries,
+// so a load test can compress weeks of simulated time into minutes.
+package clock
+
+import "time"
+
+const day = 24 * time.Hour
+
+type Clock interface {
+	Now() time.Time
+}
+
+// Wall is the real time
+type Wall struct{}
+
+func (Wall) Now() time.Time {
+	return time.Now()
+}
+
+// Simulated runs from epoch faster than the wall clock, one simulated day lasts dayLength of wall time.
+// It starts at creation, create it right before the load.
+type Simulated struct {
+	epoch time.Time
+	start time.Time
+	speed float64
+}
+
+// NewSimulated starts a clock at epoch, zero epoch is the current time
+func NewSimulated(epoch time.Time, dayLength time.Duration) *Simulated {
+	start := time.Now()
+	if epoch.IsZero() {
+		epoch = start
+	}
+
+	return &Simulated{
+		epoch: epoch,
+		start: start,
+		speed: float64(day) / float64(dayLength),
+	}
+}
+
+func (s *Simulated) Now() time.Time {
+	return s.epoch.Add(s.Simulated(time.Since(s.start)))
+}
+
+// Day is the number of whole simulated days since epoch
+func (s *Simulated) Day() int {
+	return int(s.Now().Sub(s.epoch) / day)
+}
+
+// Simulated converts a wall duration to the simulated one
+func (s *Simulated) Simulated(wall time.Duration) time.Duration {
+	return time.Duration(float64(wall) * s.speed)
+}
+
+// Wall converts a simulated duration to the wall one, e.g. to schedule something once per simulated day
+func (s *Simulated) Wall(simulated time.Duration) time.Duration {
+	return time.Duration(float64(simulated) / s.speed)
+}
+
+// scaled is a clock running at a different speed than the wall one
+type scaled interface {
+	Simulated(wall time.Duration) time.Duration
+	Wall(simulated time.Duration) time.Duration
+}
+
+// ToWall converts a duration of c to the wall one, nil and Wall clocks keep it as is
+func ToWall(c Clock, d time.Duration) time.Duration {
+	if s, ok := c.(scaled); ok {
+		return s.Wall(d)
+	}
+
+	return d
+}
+
+// FromWall converts a wall duration to the one of c, nil and Wall clocks keep it as is
+func FromWall(c Clock, wall time.Duration) time.Duration {
+	if s, ok := c.(scaled); ok {
+		return s.Simulated(wall)
+	}
+
+	return wall
+}
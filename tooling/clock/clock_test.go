@@ -0,0 +1,33 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulated(t *testing.T) {
+	epoch := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewSimulated(epoch, time.Second)
+
+	assert.Equal(t, time.Second, c.Wall(24*time.Hour))
+	assert.Equal(t, 7*24*time.Hour, c.Simulated(7*time.Second))
+
+	time.Sleep(100 * time.Millisecond)
+
+	// 100ms of wall time is 2.4 simulated hours
+	now := c.Now()
+	assert.True(t, now.After(epoch.Add(2*time.Hour)))
+	assert.True(t, now.Before(epoch.Add(6*time.Hour)))
+	assert.Zero(t, c.Day())
+}
+
+func TestConversions(t *testing.T) {
+	c := NewSimulated(time.Time{}, time.Minute)
+
+	assert.Equal(t, time.Minute, ToWall(c, 24*time.Hour))
+	assert.Equal(t, 48*time.Hour, FromWall(c, 2*time.Minute))
+	assert.Equal(t, time.Hour, ToWall(Wall{}, time.Hour))
+	assert.Equal(t, time.Hour, FromWall(nil, time.Hour))
+}
@@ -3,6 +3,8 @@ package db
 import (
 	"database/sql"
 	"github.com/rusinikita/system-design-trainer/med-care-app-cache/db"
+	"github.com/rusinikita/system-design-trainer/tooling/clock"
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
 	"log"
 	"os"
 	"time"
@@ -12,20 +14,51 @@ import (
 )
 
 func Conn() *sql.DB {
-	// Load .env file from the root directory
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
-	}
+	loadEnv()
 
 	db, err := sql.Open(os.Getenv("DB_DRIVER"), os.Getenv("DB_CONNECT"))
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	configurePool(db)
+
+	return db
+}
+
+// InstrumentedConn is Conn emitting a span for every query
+func InstrumentedConn(obs metrics.Obs) *DB {
+	loadEnv()
+
+	db, err := Open(os.Getenv("DB_DRIVER"), os.Getenv("DB_CONNECT"), obs)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	configurePool(db.DB)
+
+	return db
+}
+
+func loadEnv() {
+	// Load .env file from the root directory
+	if err := godotenv.Load(); err != nil {
+		log.Fatal("Error loading .env file")
+	}
+}
+
+func configurePool(db *sql.DB) {
 	// Set connection pool settings
 	db.SetMaxOpenConns(25)
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(5 * time.Minute)
+}
+
+// StatsConn opens a single connection pool for samplers, so they don't take connections from the load
+func StatsConn() *sql.DB {
+	db := Conn()
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
 
 	return db
 }
@@ -33,5 +66,12 @@ func Conn() *sql.DB {
 func RepoConn() (*sql.DB, *db.DashboardRepository) {
 	d := Conn()
 
-	return d, db.NewDashboardRepository(d)
+	return d, db.NewDashboardRepository(d, clock.Wall{})
+}
+
+// InstrumentedRepoConn is RepoConn with a span for every repository query, the repository tells time by clk
+func InstrumentedRepoConn(obs metrics.Obs, clk clock.Clock) (*DB, *db.DashboardRepository) {
+	d := InstrumentedConn(obs)
+
+	return d, db.NewDashboardRepository(d, clk)
 }
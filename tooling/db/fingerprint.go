@@ -0,0 +1,50 @@
+package db
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+)
+
+var (
+	queryNameComment = regexp.MustCompile(`(?m)^\s*--\s*name:\s*(\S+)`)
+	lineComment      = regexp.MustCompile(`--[^\n]*`)
+	blockComment     = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	stringLiteral    = regexp.MustCompile(`'(?:[^']|'')*'`)
+	placeholder      = regexp.MustCompile(`\$\d+`)
+	numberLiteral    = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	valuesList       = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	whitespace       = regexp.MustCompile(`\s+`)
+)
+
+// Fingerprint normalizes a query: no comments, literals and placeholders are "?", lists of them are "(?)",
+// whitespace is collapsed and text is lowercase. Queries differing only in arguments get the same fingerprint.
+func Fingerprint(query string) string {
+	q := blockComment.ReplaceAllString(query, " ")
+	q = lineComment.ReplaceAllString(q, " ")
+	q = stringLiteral.ReplaceAllString(q, "?")
+	q = placeholder.ReplaceAllString(q, "?")
+	q = numberLiteral.ReplaceAllString(q, "?")
+	q = valuesList.ReplaceAllString(q, "(?)")
+	q = whitespace.ReplaceAllString(q, " ")
+
+	return strings.ToLower(strings.TrimSpace(q))
+}
+
+// spanKey names a query span: "-- name: X" comment in the query gives "sql.X", so it doesn't clash with
+// the caller span of the same name, otherwise it's the first keyword and a short hash of the fingerprint, e.g. "sql WITH 1a2b3c4d"
+func spanKey(query string) string {
+	if m := queryNameComment.FindStringSubmatch(query); m != nil {
+		return "sql." + m[1]
+	}
+
+	fingerprint := Fingerprint(query)
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(fingerprint))
+
+	verb, _, _ := strings.Cut(fingerprint, " ")
+
+	return fmt.Sprintf("sql %s %08x", strings.ToUpper(verb), h.Sum32())
+}
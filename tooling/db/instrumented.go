@@ -0,0 +1,440 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+)
+
+// DB is *sql.DB emitting a span for every Query, QueryRow and Exec call. A span is keyed by the query
+// (see spanKey), has the rows bucket attribute and two children: sql.wait for a pool connection
+// and sql.exec for the time from sending the query to closing its rows.
+// Calls inside transactions are not instrumented.
+type DB struct {
+	*sql.DB
+	obs metrics.Obs
+}
+
+type queryStateKey struct{}
+
+// Open is sql.Open wrapping the driver, so query execution is measured on the connection level
+func Open(driverName, dsn string, obs metrics.Obs) (*DB, error) {
+	// sql.Open doesn't connect, it's only the way to find a registered driver
+	probe, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := probe.Driver()
+	_ = probe.Close()
+
+	c := &connector{dsn: dsn, driver: d}
+	if dc, ok := d.(driver.DriverContext); ok {
+		c.inner, err = dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open connector: %v", err)
+		}
+	}
+
+	return &DB{DB: sql.OpenDB(c), obs: obs}, nil
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, q := d.start(ctx, query)
+
+	rows, err := d.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		q.finish(err)
+	}
+
+	return rows, err
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, q := d.start(ctx, query)
+
+	row := d.DB.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != nil {
+		q.finish(err)
+	}
+
+	return row
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, q := d.start(ctx, query)
+
+	result, err := d.DB.ExecContext(ctx, query, args...)
+	q.finish(err)
+
+	return result, err
+}
+
+func (d *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	return d.QueryContext(context.Background(), query, args...)
+}
+
+func (d *DB) QueryRow(query string, args ...any) *sql.Row {
+	return d.QueryRowContext(context.Background(), query, args...)
+}
+
+func (d *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return d.ExecContext(context.Background(), query, args...)
+}
+
+func (d *DB) start(ctx context.Context, query string) (context.Context, *queryState) {
+	key := spanKey(query)
+
+	ctx, span := d.obs.Start(ctx, key)
+	q := &queryState{
+		obs:   d.obs,
+		ctx:   ctx,
+		attrs: []metrics.Attr{metrics.String("query", key)},
+		span:  span,
+	}
+	_, q.wait = d.obs.Start(ctx, "sql.wait", q.attrs...)
+
+	return context.WithValue(ctx, queryStateKey{}, q), q
+}
+
+// queryState follows one call from the pool to closed rows
+type queryState struct {
+	obs   metrics.Obs
+	ctx   context.Context
+	attrs []metrics.Attr
+
+	mu       sync.Mutex
+	span     metrics.Span
+	wait     metrics.Span
+	exec     metrics.Span
+	rows     int64
+	finished bool
+}
+
+func queryStateFrom(ctx context.Context) *queryState {
+	q, _ := ctx.Value(queryStateKey{}).(*queryState)
+
+	return q
+}
+
+// acquired is called by the connection, the first call ends pool waiting
+func (q *queryState) acquired() {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.exec != nil || q.finished {
+		return
+	}
+
+	q.wait.Done(nil)
+	_, q.exec = q.obs.Start(q.ctx, "sql.exec", q.attrs...)
+}
+
+func (q *queryState) addRows(n int64) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	q.rows += n
+	q.mu.Unlock()
+}
+
+func (q *queryState) finish(err error) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.finished {
+		return
+	}
+	q.finished = true
+
+	if q.exec == nil {
+		q.wait.Done(err)
+	} else {
+		q.exec.Done(err)
+	}
+
+	q.span.SetAttributes(metrics.String("rows", rowsBucket(q.rows)))
+	q.span.Done(err)
+}
+
+func rowsBucket(rows int64) string {
+	switch {
+	case rows == 0:
+		return "0"
+	case rows == 1:
+		return "1"
+	case rows <= 10:
+		return "2-10"
+	case rows <= 100:
+		return "11-100"
+	case rows <= 1000:
+		return "101-1000"
+	default:
+		return "1000+"
+	}
+}
+
+// driverFailed tells errors of the query from errors database/sql handles by retrying or falling back
+func driverFailed(err error) bool {
+	return err != nil && !errors.Is(err, driver.ErrSkip) && !errors.Is(err, driver.ErrBadConn)
+}
+
+type connector struct {
+	dsn    string
+	driver driver.Driver
+	inner  driver.Connector
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	var inner driver.Conn
+	var err error
+
+	if c.inner != nil {
+		inner, err = c.inner.Connect(ctx)
+	} else {
+		inner, err = c.driver.Open(c.dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{inner: inner}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// conn passes everything to the driver connection, marking query state on the way
+type conn struct {
+	inner driver.Conn
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	q := queryStateFrom(ctx)
+	q.acquired()
+
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.inner.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.inner.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &stmtWrapper{inner: stmt}, nil
+}
+
+func (c *conn) Close() error {
+	return c.inner.Close()
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.inner.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+
+	// fallback for drivers without BeginTx
+	return c.inner.Begin()
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.inner.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	q := queryStateFrom(ctx)
+	q.acquired()
+
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if driverFailed(err) {
+		q.finish(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &rowsWrapper{inner: rows, query: q}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.inner.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	q := queryStateFrom(ctx)
+	q.acquired()
+
+	result, err := execer.ExecContext(ctx, query, args)
+	if err == nil {
+		if n, rowsErr := result.RowsAffected(); rowsErr == nil {
+			q.addRows(n)
+		}
+	}
+
+	return result, err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.inner.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.inner.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.inner.(driver.Validator); ok {
+		return v.IsValid()
+	}
+
+	return true
+}
+
+func (c *conn) CheckNamedValue(v *driver.NamedValue) error {
+	if checker, ok := c.inner.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(v)
+	}
+
+	return driver.ErrSkip
+}
+
+type stmtWrapper struct {
+	inner driver.Stmt
+}
+
+func (s *stmtWrapper) Close() error {
+	return s.inner.Close()
+}
+
+func (s *stmtWrapper) NumInput() int {
+	return s.inner.NumInput()
+}
+
+func (s *stmtWrapper) Exec(args []driver.Value) (driver.Result, error) {
+	// database/sql calls ExecContext, this is only for the interface
+	return s.inner.Exec(args)
+}
+
+func (s *stmtWrapper) Query(args []driver.Value) (driver.Rows, error) {
+	// database/sql calls QueryContext, this is only for the interface
+	return s.inner.Query(args)
+}
+
+func (s *stmtWrapper) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	q := queryStateFrom(ctx)
+
+	var result driver.Result
+	var err error
+	if e, ok := s.inner.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(ctx, args)
+	} else {
+		// fallback for drivers without context support
+		result, err = s.inner.Exec(namedValues(args))
+	}
+
+	if err == nil {
+		if n, rowsErr := result.RowsAffected(); rowsErr == nil {
+			q.addRows(n)
+		}
+	}
+
+	return result, err
+}
+
+func (s *stmtWrapper) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	q := queryStateFrom(ctx)
+
+	var rows driver.Rows
+	var err error
+	if e, ok := s.inner.(driver.StmtQueryContext); ok {
+		rows, err = e.QueryContext(ctx, args)
+	} else {
+		// fallback for drivers without context support
+		rows, err = s.inner.Query(namedValues(args))
+	}
+
+	if driverFailed(err) {
+		q.finish(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &rowsWrapper{inner: rows, query: q}, nil
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+
+	return values
+}
+
+// rowsWrapper counts rows, closing it finishes the query span
+type rowsWrapper struct {
+	inner driver.Rows
+	query *queryState
+	err   error
+}
+
+func (r *rowsWrapper) Columns() []string {
+	return r.inner.Columns()
+}
+
+func (r *rowsWrapper) Next(dest []driver.Value) error {
+	err := r.inner.Next(dest)
+	switch {
+	case err == nil:
+		r.query.addRows(1)
+	case !errors.Is(err, io.EOF):
+		r.err = err
+	}
+
+	return err
+}
+
+func (r *rowsWrapper) Close() error {
+	err := r.inner.Close()
+	r.query.finish(errors.Join(r.err, err))
+
+	return err
+}
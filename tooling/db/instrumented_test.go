@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+	"github.com/rusinikita/system-design-trainer/tooling/metrics/metricstest"
+)
+
+var errFakeQuery = errors.New("fake query failed")
+
+func init() {
+	sql.Register("fakedb", fakeDriver{})
+}
+
+// fakeDriver returns as many rows as the first argument, the query "fail" fails
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if query == "fail" {
+		return nil, errFakeQuery
+	}
+
+	return &fakeRows{left: args[0].Value.(int64)}, nil
+}
+
+func (fakeConn) ExecContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(args[0].Value.(int64)), nil
+}
+
+type fakeRows struct {
+	left int64
+}
+
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.left == 0 {
+		return io.EOF
+	}
+
+	dest[0] = r.left
+	r.left--
+
+	return nil
+}
+
+func openFake(t *testing.T) (*DB, *metricstest.Recorder) {
+	obs := metricstest.NewRecorder()
+
+	db, err := Open("fakedb", "", obs)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db, obs
+}
+
+func TestQuerySpans(t *testing.T) {
+	db, obs := openFake(t)
+
+	query := "-- name: GetNumbers\nSELECT n FROM numbers WHERE n < $1"
+
+	rows, err := db.QueryContext(context.Background(), query, 3)
+	require.NoError(t, err)
+
+	var count int
+	for rows.Next() {
+		count++
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	assert.Equal(t, 3, count)
+
+	obs.AssertOK(t, "sql.GetNumbers")
+	obs.AssertAttr(t, "sql.GetNumbers", metrics.String("rows", "2-10"))
+	obs.AssertAttr(t, "sql.exec", metrics.String("query", "sql.GetNumbers"))
+	obs.AssertCovers(t, "sql.GetNumbers", "sql.wait", "sql.exec")
+	obs.AssertAllDone(t)
+}
+
+func TestQueryRowAndExecSpans(t *testing.T) {
+	db, obs := openFake(t)
+
+	var n int64
+	require.NoError(t, db.QueryRowContext(context.Background(), "SELECT n FROM numbers WHERE n = 1", 1).Scan(&n))
+
+	key := spanKey("SELECT n FROM numbers WHERE n = 1")
+	obs.AssertOK(t, key)
+	obs.AssertAttr(t, key, metrics.String("rows", "1"))
+
+	obs.Reset()
+
+	_, err := db.ExecContext(context.Background(), "DELETE FROM numbers", 500)
+	require.NoError(t, err)
+
+	key = spanKey("DELETE FROM numbers")
+	obs.AssertOK(t, key)
+	obs.AssertAttr(t, key, metrics.String("rows", "101-1000"))
+	obs.AssertAllDone(t)
+}
+
+func TestQueryErrorSpan(t *testing.T) {
+	db, obs := openFake(t)
+
+	_, err := db.QueryContext(context.Background(), "fail")
+	require.ErrorIs(t, err, errFakeQuery)
+
+	obs.AssertError(t, spanKey("fail"), errFakeQuery)
+	obs.AssertError(t, "sql.exec", errFakeQuery)
+	obs.AssertOK(t, "sql.wait")
+	obs.AssertAllDone(t)
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint(`
+		SELECT * FROM articles -- feed
+		WHERE id IN (1, 2, 3) AND title = 'it''s' AND user_id = $1 /* comment */
+	`)
+	b := Fingerprint("select * from articles where id in (4) and title = 'x' and user_id = $2")
+
+	assert.Equal(t, "select * from articles where id in (?) and title = ? and user_id = ?", a)
+	assert.Equal(t, a, b)
+
+	assert.Equal(t, spanKey("SELECT 1"), spanKey("select   2"))
+	assert.Regexp(t, `^sql SELECT [0-9a-f]{8}$`, spanKey("SELECT 1"))
+	assert.Equal(t, "sql.GetArticleFeed", spanKey("\n\t\t-- name: GetArticleFeed\n\t\tWITH x AS (SELECT 1)"))
+}
@@ -0,0 +1,250 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+)
+
+// sqlStateNotInPrerequisiteState is returned by pg_stat_statements when it isn't in shared_preload_libraries
+const sqlStateNotInPrerequisiteState = "55000"
+
+// DashboardStatements match med-care-app-cache repository queries in pg_stat_statements,
+// names are the query span keys, so client and server side stats line up
+var DashboardStatements = map[string]string{
+	"sql.GetArticleFeed":         "%JOIN article_segments ags ON%",
+	"sql.GetLatestCarePlanSteps": "%FROM care_plan_steps cps%",
+}
+
+type pgDatabaseStats struct {
+	blksHit      int64
+	blksRead     int64
+	tupReturned  int64
+	tupFetched   int64
+	deadlocks    int64
+	xactCommit   int64
+	xactRollback int64
+}
+
+type pgStatementStats struct {
+	calls     int64
+	totalTime float64 // milliseconds
+	rows      int64
+}
+
+// PGStatsSampler polls Postgres server-side statistics for the current database:
+// pg_stat_database deltas, pg_stat_activity wait events and, when the extension is loaded,
+// pg_stat_statements deltas of the given statements.
+//
+// Use a separate connection pool (see StatsConn), so sampling doesn't compete with the load for connections.
+type PGStatsSampler struct {
+	db         *sql.DB
+	statements map[string]string
+
+	primed         bool
+	last           pgDatabaseStats
+	lastStatements map[string]pgStatementStats
+	// statementsChecked is set once the extension is found installed
+	statementsChecked bool
+	// statementsOff is set when pg_stat_statements is not installed or not preloaded
+	statementsOff bool
+}
+
+// NewPGStatsSampler creates a sampler, statements maps a name to a LIKE pattern of query text
+func NewPGStatsSampler(db *sql.DB, statements map[string]string) *PGStatsSampler {
+	return &PGStatsSampler{
+		db:             db,
+		statements:     statements,
+		lastStatements: map[string]pgStatementStats{},
+	}
+}
+
+func (s *PGStatsSampler) Sample(ctx context.Context) ([]metrics.GaugeValue, error) {
+	var values []metrics.GaugeValue
+
+	dbValues, err := s.sampleDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values = append(values, dbValues...)
+
+	activity, err := s.sampleActivity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values = append(values, activity...)
+
+	if !s.statementsOff && len(s.statements) > 0 {
+		statements, err := s.sampleStatements(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, statements...)
+	}
+
+	s.primed = true
+
+	return values, nil
+}
+
+func (s *PGStatsSampler) sampleDatabase(ctx context.Context) ([]metrics.GaugeValue, error) {
+	var stats pgDatabaseStats
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT blks_hit, blks_read, tup_returned, tup_fetched, deadlocks, xact_commit, xact_rollback
+		FROM pg_stat_database
+		WHERE datname = current_database()
+	`).Scan(
+		&stats.blksHit,
+		&stats.blksRead,
+		&stats.tupReturned,
+		&stats.tupFetched,
+		&stats.deadlocks,
+		&stats.xactCommit,
+		&stats.xactRollback,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pg_stat_database: %v", err)
+	}
+
+	last := s.last
+	s.last = stats
+
+	// counters are cumulative since server start, the first sample is only a baseline
+	if !s.primed {
+		return nil, nil
+	}
+
+	hit, read := stats.blksHit-last.blksHit, stats.blksRead-last.blksRead
+	hitRatio := 1.0
+	if hit+read > 0 {
+		hitRatio = float64(hit) / float64(hit+read)
+	}
+
+	return []metrics.GaugeValue{
+		{Name: "pg.cache_hit_ratio", Value: hitRatio},
+		{Name: "pg.blocks_read", Value: float64(read)},
+		{Name: "pg.tuples_returned", Value: float64(stats.tupReturned - last.tupReturned)},
+		{Name: "pg.tuples_fetched", Value: float64(stats.tupFetched - last.tupFetched)},
+		{Name: "pg.deadlocks", Value: float64(stats.deadlocks - last.deadlocks)},
+		{Name: "pg.commits", Value: float64(stats.xactCommit - last.xactCommit)},
+		{Name: "pg.rollbacks", Value: float64(stats.xactRollback - last.xactRollback)},
+	}, nil
+}
+
+func (s *PGStatsSampler) sampleActivity(ctx context.Context) ([]metrics.GaugeValue, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			COALESCE(wait_event_type, 'CPU'),
+			COALESCE(wait_event, 'CPU'),
+			count(*)
+		FROM pg_stat_activity
+		WHERE datname = current_database()
+			AND state = 'active'
+			AND pid <> pg_backend_pid()
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pg_stat_activity: %v", err)
+	}
+	defer rows.Close()
+
+	var values []metrics.GaugeValue
+	for rows.Next() {
+		var eventType, event string
+		var count int64
+		if err := rows.Scan(&eventType, &event, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan pg_stat_activity: %v", err)
+		}
+
+		values = append(values, metrics.GaugeValue{
+			Name: "pg.active_sessions",
+			Attrs: []metrics.Attr{
+				metrics.String("wait_event_type", eventType),
+				metrics.String("wait_event", event),
+			},
+			Value: float64(count),
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pg_stat_activity: %v", err)
+	}
+
+	return values, nil
+}
+
+func (s *PGStatsSampler) sampleStatements(ctx context.Context) ([]metrics.GaugeValue, error) {
+	if !s.statementsChecked {
+		var installed bool
+		err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_stat_statements')`).Scan(&installed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check pg_stat_statements: %v", err)
+		}
+
+		if !installed {
+			s.statementsOff = true
+			return nil, nil
+		}
+
+		s.statementsChecked = true
+	}
+
+	var values []metrics.GaugeValue
+	for _, name := range slices.Sorted(maps.Keys(s.statements)) {
+		var stats pgStatementStats
+
+		err := s.db.QueryRowContext(ctx, `
+			SELECT COALESCE(sum(calls), 0), COALESCE(sum(total_exec_time), 0), COALESCE(sum(rows), 0)
+			FROM pg_stat_statements
+			WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+				AND query LIKE $1
+		`, s.statements[name]).Scan(&stats.calls, &stats.totalTime, &stats.rows)
+		if sqlState(err) == sqlStateNotInPrerequisiteState {
+			// the extension is created but the library isn't in shared_preload_libraries
+			s.statementsOff = true
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pg_stat_statements: %v", err)
+		}
+
+		last, seen := s.lastStatements[name]
+		s.lastStatements[name] = stats
+
+		if !seen {
+			continue
+		}
+
+		calls := stats.calls - last.calls
+		meanSeconds := 0.0
+		if calls > 0 {
+			meanSeconds = (stats.totalTime - last.totalTime) / float64(calls) / 1000
+		}
+
+		attrs := []metrics.Attr{metrics.String("statement", name)}
+		values = append(values,
+			metrics.GaugeValue{Name: "pg.statement.calls", Attrs: attrs, Value: float64(calls)},
+			metrics.GaugeValue{Name: "pg.statement.mean_exec_seconds", Attrs: attrs, Value: meanSeconds},
+			metrics.GaugeValue{Name: "pg.statement.rows", Attrs: attrs, Value: float64(stats.rows - last.rows)},
+		)
+	}
+
+	return values, nil
+}
+
+// sqlState returns SQLSTATE of a Postgres error, lib/pq and pgx errors both have it
+func sqlState(err error) string {
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState()
+	}
+
+	return ""
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rusinikita/system-design-trainer/med-care-app-cache/db"
+	"github.com/rusinikita/system-design-trainer/tooling/clock"
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+)
+
+func TestSQLState(t *testing.T) {
+	notLoaded := fmt.Errorf("query failed: %w", &pq.Error{Code: sqlStateNotInPrerequisiteState})
+
+	assert.Equal(t, sqlStateNotInPrerequisiteState, sqlState(notLoaded))
+	assert.Empty(t, sqlState(errors.New("connection reset")))
+	assert.Empty(t, sqlState(nil))
+}
+
+func TestPGStatsSampler(t *testing.T) {
+	conn, err := sql.Open("postgres", "postgres://localhost:5432/testdb?sslmode=disable")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	sampler := NewPGStatsSampler(conn, DashboardStatements)
+
+	// the first sample is a baseline of cumulative counters
+	values, err := sampler.Sample(ctx)
+	require.NoError(t, err)
+	for _, v := range values {
+		assert.Equal(t, "pg.active_sessions", v.Name)
+	}
+
+	repo := db.NewDashboardRepository(conn, clock.Wall{})
+	for range 5 {
+		_, err := repo.GetLatestCarePlanSteps(ctx, 1)
+		require.NoError(t, err)
+	}
+
+	// backends flush their stats with a delay, deltas are summed up until they show up
+	var commits, calls float64
+	for deadline := time.Now().Add(15 * time.Second); commits == 0 || calls == 0; time.Sleep(500 * time.Millisecond) {
+		require.True(t, time.Now().Before(deadline), "no stats: commits %v, calls %v", commits, calls)
+
+		values, err := sampler.Sample(ctx)
+		require.NoError(t, err)
+
+		for _, v := range values {
+			switch {
+			case v.Name == "pg.commits":
+				commits += v.Value
+			case v.Name == "pg.statement.calls" && v.Attrs[0] == metrics.String("statement", "sql.GetLatestCarePlanSteps"):
+				calls += v.Value
+			case v.Name == "pg.cache_hit_ratio":
+				assert.InDelta(t, 0.5, v.Value, 0.5)
+			}
+		}
+	}
+
+	assert.GreaterOrEqual(t, calls, 5.0)
+}
@@ -0,0 +1,130 @@
+// Package loadgen drives load against the exercise code and reports it through metrics.Obs.
+// The tested code emits its own spans, the generator reports only requests it made and workers it runs.
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+)
+
+// Request is one call of the tested code. r is owned by the calling worker, so it's safe to use without locks.
+type Request func(ctx context.Context, r *rand.Rand) error
+
+// Load drives requests for a run, ClosedLoop and OpenLoop implement it
+type Load interface {
+	Run(ctx context.Context, obs metrics.Obs, request Request) error
+}
+
+// ClosedLoop is a fixed number of virtual users, each sends the next request only after the previous one
+// is answered. Offered load drops when the system slows down, use it to find max throughput, not latency.
+type ClosedLoop struct {
+	// Workers is the number of virtual users
+	Workers int
+	// RampUp starts workers evenly over this period instead of all at once
+	RampUp time.Duration
+	// Duration is the whole run including ramp-up
+	Duration time.Duration
+	// ThinkTime is a pause of every worker between its requests
+	ThinkTime time.Duration
+	// Seed makes per-worker random sources reproducible, zero picks a random seed
+	Seed uint64
+}
+
+func (c ClosedLoop) validate() error {
+	switch {
+	case c.Workers <= 0:
+		return errors.New("workers must be positive")
+	case c.Duration <= 0:
+		return errors.New("duration must be positive")
+	case c.RampUp < 0 || c.RampUp > c.Duration:
+		return errors.New("ramp-up must be within duration")
+	case c.ThinkTime < 0:
+		return errors.New("think time can't be negative")
+	}
+
+	return nil
+}
+
+// Run blocks until Duration passes or ctx is canceled. Workers stop starting requests then,
+// while requests in flight finish with ctx, so the tail of the run isn't reported as canceled.
+//
+// It reports loadgen.requests counter with result=ok|error and loadgen.workers gauge of running workers.
+func (c ClosedLoop) Run(ctx context.Context, obs metrics.Obs, request Request) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	seed := c.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+
+	stop, cancel := context.WithTimeout(ctx, c.Duration)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		running atomic.Int64
+	)
+
+	for i := 0; i < c.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if !sleep(stop, c.RampUp*time.Duration(i)/time.Duration(c.Workers)) {
+				return
+			}
+
+			obs.SetGauge("loadgen.workers", float64(running.Add(1)))
+			defer func() {
+				obs.SetGauge("loadgen.workers", float64(running.Add(-1)))
+			}()
+
+			r := rand.New(rand.NewPCG(seed, uint64(i)))
+			for stop.Err() == nil {
+				report(obs, request(ctx, r))
+
+				if !sleep(stop, c.ThinkTime) {
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+func report(obs metrics.Obs, err error, attrs ...metrics.Attr) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+
+	obs.Add("loadgen.requests", 1, append(attrs, metrics.String("result", result))...)
+}
+
+// sleep waits for d, it returns false when ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
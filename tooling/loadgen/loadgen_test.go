@@ -0,0 +1,79 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+	"github.com/rusinikita/system-design-trainer/tooling/metrics/metricstest"
+)
+
+func TestClosedLoop(t *testing.T) {
+	rec := metricstest.NewRecorder()
+
+	var (
+		mu      sync.Mutex
+		starts  []time.Time
+		sources = map[*rand.Rand]bool{}
+	)
+
+	begin := time.Now()
+	err := ClosedLoop{
+		Workers:   4,
+		RampUp:    100 * time.Millisecond,
+		Duration:  300 * time.Millisecond,
+		ThinkTime: 20 * time.Millisecond,
+		Seed:      1,
+	}.Run(context.Background(), rec, func(ctx context.Context, r *rand.Rand) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !sources[r] {
+			starts = append(starts, time.Now())
+		}
+		sources[r] = true
+
+		if r.IntN(2) == 0 {
+			return errors.New("failed")
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(begin), 400*time.Millisecond)
+
+	// every worker has its own random source and starts later than the previous one
+	require.Len(t, starts, 4)
+	assert.Greater(t, starts[3].Sub(starts[0]), 60*time.Millisecond)
+
+	ok, failed := rec.Counter("loadgen.requests", metrics.String("result", "ok")), rec.Counter("loadgen.requests", metrics.String("result", "error"))
+	assert.Positive(t, ok)
+	assert.Positive(t, failed)
+	// think time bounds requests of every worker to duration / think time
+	assert.LessOrEqual(t, ok+failed, int64(4*15+4))
+
+	rec.AssertGauge(t, "loadgen.workers", 0)
+}
+
+func TestClosedLoopStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := ClosedLoop{Workers: 1, Duration: time.Minute}.Run(ctx, metricstest.NewRecorder(), func(context.Context, *rand.Rand) error {
+		calls++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Zero(t, calls)
+
+	err = ClosedLoop{Workers: 0, Duration: time.Minute}.Run(context.Background(), metricstest.NewRecorder(), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,132 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rusinikita/system-design-trainer/tooling/clock"
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+)
+
+// idleCheck is how often a zero rate profile is checked again
+const idleCheck = 10 * time.Millisecond
+
+// Arrivals is the distribution of intervals between open loop requests
+type Arrivals int
+
+const (
+	// Poisson arrivals have exponential intervals, like independent users do
+	Poisson Arrivals = iota
+	// Uniform arrivals are evenly spaced
+	Uniform
+)
+
+// OpenLoop sends requests at the profile rate no matter how slow responses are.
+// Unlike ClosedLoop it doesn't hide saturation: once the system can't keep up, requests queue
+// and response time grows, which shows the knee of the latency curve.
+type OpenLoop struct {
+	// Profile gets the time since the run start on Clock, while its rate is per wall second
+	Profile  Profile
+	Arrivals Arrivals
+	// Clock is the time of the tested code, e.g. clock.Simulated to follow a daily pattern within minutes.
+	// Nil is the wall clock.
+	Clock clock.Clock
+	// Duration is the whole run, requests in flight at its end are awaited
+	Duration time.Duration
+	// MaxInFlight caps concurrent requests to protect the generator itself, arrivals above it
+	// are reported as result=dropped. Zero is unlimited.
+	MaxInFlight int
+	// Seed makes arrivals and request random sources reproducible, zero picks a random seed
+	Seed uint64
+}
+
+func (o OpenLoop) validate() error {
+	switch {
+	case o.Profile == nil:
+		return errors.New("profile is required")
+	case o.Duration <= 0:
+		return errors.New("duration must be positive")
+	case o.MaxInFlight < 0:
+		return errors.New("max in flight can't be negative")
+	}
+
+	return nil
+}
+
+// Run blocks until Duration passes or ctx is canceled and all requests in flight finish.
+// Every request context carries its scheduled time as metrics intended start, so spans report
+// response time including the generator lag behind schedule.
+//
+// It reports loadgen.requests counter with result=ok|error|dropped, loadgen.in_flight
+// and loadgen.target_rps gauges.
+func (o OpenLoop) Run(ctx context.Context, obs metrics.Obs, request Request) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+
+	seed := o.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+
+	stop, cancel := context.WithTimeout(ctx, o.Duration)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		inFlight atomic.Int64
+	)
+
+	arrivals := rand.New(rand.NewPCG(seed, 0))
+	start := time.Now()
+	next := start
+
+	for seq := uint64(1); ; seq++ {
+		rps := o.Profile(clock.FromWall(o.Clock, next.Sub(start)))
+		obs.SetGauge("loadgen.target_rps", rps)
+
+		if rps <= 0 {
+			next = next.Add(idleCheck)
+			if !sleep(stop, time.Until(next)) {
+				break
+			}
+			continue
+		}
+
+		next = next.Add(o.interval(arrivals, rps))
+		if !sleep(stop, time.Until(next)) {
+			break
+		}
+
+		if o.MaxInFlight > 0 && inFlight.Load() >= int64(o.MaxInFlight) {
+			obs.Add("loadgen.requests", 1, metrics.String("result", "dropped"))
+			continue
+		}
+
+		obs.SetGauge("loadgen.in_flight", float64(inFlight.Add(1)))
+
+		wg.Add(1)
+		go func(intended time.Time) {
+			defer wg.Done()
+
+			report(obs, request(metrics.WithIntendedStart(ctx, intended), rand.New(rand.NewPCG(seed, seq))))
+			obs.SetGauge("loadgen.in_flight", float64(inFlight.Add(-1)))
+		}(next)
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+func (o OpenLoop) interval(r *rand.Rand, rps float64) time.Duration {
+	if o.Arrivals == Uniform {
+		return time.Duration(float64(time.Second) / rps)
+	}
+
+	return time.Duration(r.ExpFloat64() / rps * float64(time.Second))
+}
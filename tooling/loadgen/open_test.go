@@ -0,0 +1,69 @@
+package loadgen
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+	"github.com/rusinikita/system-design-trainer/tooling/metrics/metricstest"
+)
+
+func TestOpenLoopKeepsRate(t *testing.T) {
+	for _, arrivals := range []Arrivals{Uniform, Poisson} {
+		rec := metricstest.NewRecorder()
+
+		// slow responses don't lower the offered load, unlike in closed loop
+		err := OpenLoop{
+			Profile:  Constant(200),
+			Arrivals: arrivals,
+			Duration: 500 * time.Millisecond,
+			Seed:     1,
+		}.Run(context.Background(), rec, func(ctx context.Context, r *rand.Rand) error {
+			_, span := rec.Start(ctx, "request")
+			time.Sleep(50 * time.Millisecond)
+			span.Done(nil)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		assert.InDelta(t, 100, rec.Counter("loadgen.requests", metrics.String("result", "ok")), 30)
+		rec.AssertGauge(t, "loadgen.in_flight", 0)
+		rec.AssertGauge(t, "loadgen.target_rps", 200)
+		rec.AssertAllDone(t)
+
+		for _, s := range rec.Find("request") {
+			assert.False(t, s.Intended.IsZero())
+			assert.False(t, s.Intended.After(s.Start))
+		}
+	}
+}
+
+func TestOpenLoopMaxInFlight(t *testing.T) {
+	rec := metricstest.NewRecorder()
+	release := make(chan struct{})
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		close(release)
+	}()
+
+	err := OpenLoop{
+		Profile:     Constant(100),
+		Arrivals:    Uniform,
+		Duration:    150 * time.Millisecond,
+		MaxInFlight: 2,
+	}.Run(context.Background(), rec, func(context.Context, *rand.Rand) error {
+		<-release
+		return nil
+	})
+	require.NoError(t, err)
+
+	rec.AssertCounter(t, "loadgen.requests", 2, metrics.String("result", "ok"))
+	assert.Greater(t, rec.Counter("loadgen.requests", metrics.String("result", "dropped")), int64(8))
+}
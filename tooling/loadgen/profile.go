@@ -0,0 +1,69 @@
+package loadgen
+
+import (
+	"math"
+	"time"
+)
+
+// Profile is the target rate in requests per second at time t since the run start
+type Profile func(t time.Duration) float64
+
+// Constant keeps the same rate for the whole run
+func Constant(rps float64) Profile {
+	return func(time.Duration) float64 {
+		return rps
+	}
+}
+
+// Ramp grows the rate linearly from one value to another over a period, then holds it
+func Ramp(from, to float64, over time.Duration) Profile {
+	return func(t time.Duration) float64 {
+		if over <= 0 || t >= over {
+			return to
+		}
+
+		return from + (to-from)*float64(t)/float64(over)
+	}
+}
+
+// Step is Ramp as a staircase: the rate rises from one value to another in equal steps,
+// every level holds for an equal part of the period, so latency settles before the next step.
+func Step(from, to float64, steps int, over time.Duration) Profile {
+	if steps < 1 {
+		steps = 1
+	}
+
+	return func(t time.Duration) float64 {
+		if over <= 0 || t >= over {
+			return to
+		}
+
+		level := int(t * time.Duration(steps+1) / over)
+
+		return from + (to-from)*float64(level)/float64(steps)
+	}
+}
+
+// Spike keeps the base rate, except for the peak rate during length starting at at
+func Spike(base, peak float64, at, length time.Duration) Profile {
+	return func(t time.Duration) float64 {
+		if t >= at && t < at+length {
+			return peak
+		}
+
+		return base
+	}
+}
+
+// Sine swings the rate between low and high with the period, starting at low
+func Sine(low, high float64, period time.Duration) Profile {
+	return func(t time.Duration) float64 {
+		if period <= 0 {
+			return low
+		}
+
+		phase := 2 * math.Pi * float64(t) / float64(period)
+
+		return low + (high-low)*(1-math.Cos(phase))/2
+	}
+}
@@ -0,0 +1,37 @@
+package loadgen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		at      time.Duration
+		want    float64
+	}{
+		{"constant", Constant(50), time.Hour, 50},
+		{"ramp start", Ramp(10, 110, 10*time.Second), 0, 10},
+		{"ramp middle", Ramp(10, 110, 10*time.Second), 5 * time.Second, 60},
+		{"ramp after", Ramp(10, 110, 10*time.Second), time.Minute, 110},
+		{"step first level", Step(100, 400, 3, 40*time.Second), 9 * time.Second, 100},
+		{"step second level", Step(100, 400, 3, 40*time.Second), 10 * time.Second, 200},
+		{"step last level", Step(100, 400, 3, 40*time.Second), 39 * time.Second, 400},
+		{"spike before", Spike(10, 1000, time.Second, time.Second), 999 * time.Millisecond, 10},
+		{"spike peak", Spike(10, 1000, time.Second, time.Second), time.Second, 1000},
+		{"spike after", Spike(10, 1000, time.Second, time.Second), 2 * time.Second, 10},
+		{"sine start", Sine(10, 30, 4*time.Second), 0, 10},
+		{"sine middle", Sine(10, 30, 4*time.Second), time.Second, 20},
+		{"sine high", Sine(10, 30, 4*time.Second), 2 * time.Second, 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, tt.profile(tt.at), 1e-9)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rusinikita/system-design-trainer/tooling/clock"
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+)
+
+// Burst prepares one push, e.g. publishes an article, and returns requests of users reacting to it
+type Burst func(ctx context.Context, r *rand.Rand) ([]Request, error)
+
+// Push is a periodic correlated burst on top of background load, like users opening the app after a notification.
+// Every push calls Burst, then sends its requests with exponentially decaying arrivals: most of them
+// come right after the push, the rest trail off within Spread. It runs alongside ClosedLoop or OpenLoop.
+type Push struct {
+	Burst Burst
+	// Every is the period between pushes on Clock, e.g. 24h for a daily push, the first one happens after it too
+	Every time.Duration
+	// Spread is the wall time period in which all requests of a push arrive
+	Spread time.Duration
+	// Clock is the time of the tested code, nil is the wall clock
+	Clock clock.Clock
+	// Duration is the whole run, requests in flight at its end are awaited
+	Duration time.Duration
+	// Seed makes bursts and arrivals reproducible, zero picks a random seed
+	Seed uint64
+}
+
+func (p Push) validate() error {
+	switch {
+	case p.Burst == nil:
+		return errors.New("burst is required")
+	case p.Every <= 0:
+		return errors.New("push period must be positive")
+	case p.Spread < 0 || p.Spread > clock.ToWall(p.Clock, p.Every):
+		return errors.New("spread must be within push period")
+	case p.Duration <= 0:
+		return errors.New("duration must be positive")
+	}
+
+	return nil
+}
+
+// Run blocks until Duration passes or ctx is canceled and all requests in flight finish.
+// Requests carry their scheduled time as metrics intended start, same as in OpenLoop.
+//
+// It reports loadgen.pushes counter with result=ok|error, loadgen.push_size gauge of the last burst
+// and loadgen.requests counter with load=push besides the result.
+func (p Push) Run(ctx context.Context, obs metrics.Obs) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	seed := p.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+
+	stop, cancel := context.WithTimeout(ctx, p.Duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	r := rand.New(rand.NewPCG(seed, 0))
+	every := clock.ToWall(p.Clock, p.Every)
+
+	for push := uint64(1); sleep(stop, every); push++ {
+		requests, err := p.Burst(ctx, rand.New(rand.NewPCG(seed, push)))
+		if err != nil {
+			obs.Add("loadgen.pushes", 1, metrics.String("result", "error"))
+			continue
+		}
+		obs.Add("loadgen.pushes", 1, metrics.String("result", "ok"))
+		obs.SetGauge("loadgen.push_size", float64(len(requests)))
+
+		wg.Add(1)
+		go func(start time.Time, delays []time.Duration) {
+			defer wg.Done()
+
+			for i, request := range requests {
+				intended := start.Add(delays[i])
+				if !sleep(stop, time.Until(intended)) {
+					return
+				}
+
+				wg.Add(1)
+				go func(seq uint64) {
+					defer wg.Done()
+
+					err := request(metrics.WithIntendedStart(ctx, intended), rand.New(rand.NewPCG(seed, seq)))
+					report(obs, err, metrics.String("load", "push"))
+				}(push<<32 | uint64(i))
+			}
+		}(time.Now(), p.delays(r, len(requests)))
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// delays are sorted arrival offsets, exponential with mean of a quarter of Spread and capped by it
+func (p Push) delays(r *rand.Rand, n int) []time.Duration {
+	delays := make([]time.Duration, n)
+	for i := range delays {
+		delays[i] = min(time.Duration(r.ExpFloat64()*float64(p.Spread)/4), p.Spread)
+	}
+
+	slices.Sort(delays)
+
+	return delays
+}
@@ -0,0 +1,75 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rusinikita/system-design-trainer/tooling/clock"
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+	"github.com/rusinikita/system-design-trainer/tooling/metrics/metricstest"
+)
+
+func TestPush(t *testing.T) {
+	rec := metricstest.NewRecorder()
+
+	bursts := 0
+	err := Push{
+		Every:    100 * time.Millisecond,
+		Spread:   40 * time.Millisecond,
+		Duration: 390 * time.Millisecond,
+		Burst: func(ctx context.Context, r *rand.Rand) ([]Request, error) {
+			bursts++
+			if bursts == 2 {
+				return nil, errors.New("publish failed")
+			}
+
+			requests := make([]Request, 20)
+			for i := range requests {
+				requests[i] = func(ctx context.Context, r *rand.Rand) error {
+					_, span := rec.Start(ctx, "request")
+					span.Done(nil)
+
+					return nil
+				}
+			}
+
+			return requests, nil
+		},
+	}.Run(context.Background(), rec)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, bursts)
+	rec.AssertCounter(t, "loadgen.pushes", 2, metrics.String("result", "ok"))
+	rec.AssertCounter(t, "loadgen.pushes", 1, metrics.String("result", "error"))
+	rec.AssertCounter(t, "loadgen.requests", 40, metrics.String("load", "push"), metrics.String("result", "ok"))
+	rec.AssertGauge(t, "loadgen.push_size", 20)
+
+	// requests of a burst arrive within spread, the next burst comes a period later
+	spans := rec.Find("request")
+	require.Len(t, spans, 40)
+	assert.LessOrEqual(t, spans[19].Intended.Sub(spans[0].Intended), 40*time.Millisecond)
+	assert.Greater(t, spans[20].Intended.Sub(spans[19].Intended), 100*time.Millisecond)
+}
+
+func TestPushEverySimulatedDay(t *testing.T) {
+	rec := metricstest.NewRecorder()
+
+	err := Push{
+		Every:    24 * time.Hour,
+		Spread:   10 * time.Millisecond,
+		Duration: 250 * time.Millisecond,
+		Clock:    clock.NewSimulated(time.Time{}, 100*time.Millisecond),
+		Burst: func(ctx context.Context, r *rand.Rand) ([]Request, error) {
+			return nil, nil
+		},
+	}.Run(context.Background(), rec)
+	require.NoError(t, err)
+
+	rec.AssertCounter(t, "loadgen.pushes", 2, metrics.String("result", "ok"))
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const defaultAttrSetsLimit = 50
+
+var overflowAttrs = []Attr{Bool("overflow", true)}
+
+// Attr is a span dimension, e.g. cache=hit. Keep values low-cardinality,
+// every distinct set becomes a separate row in the aggregated log.
+type Attr struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func String(key, value string) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+func Int(key string, value int) Attr {
+	return Attr{Key: key, Value: strconv.Itoa(value)}
+}
+
+func Bool(key string, value bool) Attr {
+	return Attr{Key: key, Value: strconv.FormatBool(value)}
+}
+
+// sortAttrs returns attributes sorted by key, the last value of a repeated key wins
+func sortAttrs(attrs []Attr) []Attr {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	sorted := slices.Clone(attrs)
+	slices.Reverse(sorted)
+	slices.SortStableFunc(sorted, func(a, b Attr) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+
+	return slices.CompactFunc(sorted, func(a, b Attr) bool {
+		return a.Key == b.Key
+	})
+}
+
+// formatAttrs returns sorted attributes in "k1=v1;k2=v2" form
+func formatAttrs(sorted []Attr) string {
+	var b strings.Builder
+	for i, a := range sorted {
+		if i > 0 {
+			b.WriteByte(';')
+		}
+		b.WriteString(a.Key)
+		b.WriteByte('=')
+		b.WriteString(a.Value)
+	}
+
+	return b.String()
+}
+
+func attrsKey(attrs []Attr) string {
+	return formatAttrs(sortAttrs(attrs))
+}
+
+// dimensions limits distinct attribute sets per span name for the whole run,
+// sets above the limit are folded into a single overflow row
+type dimensions struct {
+	limit int
+	seen  map[string]map[string]struct{}
+}
+
+func newDimensions(limit int) *dimensions {
+	return &dimensions{
+		limit: limit,
+		seen:  map[string]map[string]struct{}{},
+	}
+}
+
+func (d *dimensions) attrs(name string, attrs []Attr) ([]Attr, string) {
+	sorted := sortAttrs(attrs)
+	key := formatAttrs(sorted)
+	if key == "" {
+		return nil, key
+	}
+
+	sets, ok := d.seen[name]
+	if !ok {
+		sets = map[string]struct{}{}
+		d.seen[name] = sets
+	}
+
+	if _, ok := sets[key]; ok {
+		return sorted, key
+	}
+
+	if len(sets) >= d.limit {
+		return overflowAttrs, formatAttrs(overflowAttrs)
+	}
+
+	sets[key] = struct{}{}
+
+	return sorted, key
+}
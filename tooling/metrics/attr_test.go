@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttrsKey(t *testing.T) {
+	assert.Equal(t, "", attrsKey(nil))
+	assert.Equal(t,
+		"cache=miss;page=1;strategy=cached",
+		attrsKey([]Attr{String("strategy", "cached"), String("cache", "hit"), Int("page", 1), String("cache", "miss")}),
+	)
+}
+
+func TestAggregateByAttributes(t *testing.T) {
+	dims := newDimensions(2)
+
+	results := []result{
+		{key: "GetArticleFeed", duration: 1, attrs: []Attr{String("cache", "hit")}},
+		{key: "GetArticleFeed", duration: 1, attrs: []Attr{String("cache", "hit")}},
+		{key: "GetArticleFeed", duration: 1, attrs: []Attr{String("cache", "miss")}},
+		{key: "GetArticleFeed", duration: 1, attrs: []Attr{String("cache", "stale")}},
+		{key: "GetArticleFeed", duration: 1},
+		{key: "GetLatestCarePlanSteps", duration: 1, attrs: []Attr{String("cache", "stale")}},
+	}
+
+	m := aggregate(results, dims, nil, 0, time.Now())
+
+	counts := map[dimensionKey]int64{}
+	for k, d := range m {
+		counts[k] = d.Count
+	}
+
+	assert.Equal(t, map[dimensionKey]int64{
+		{key: "GetArticleFeed", attrs: "cache=hit"}:           2,
+		{key: "GetArticleFeed", attrs: "cache=miss"}:          1,
+		{key: "GetArticleFeed", attrs: "overflow=true"}:       1,
+		{key: "GetArticleFeed", attrs: ""}:                    1,
+		{key: "GetLatestCarePlanSteps", attrs: "cache=stale"}: 1,
+	}, counts)
+}
@@ -0,0 +1,63 @@
+// Command compare reports per span key changes between metrics logs.
+// The first log is the baseline, every other log is compared to it.
+// Exits with code 1 when any candidate has a significant regression above threshold.
+//
+//	go run ./tooling/metrics/cmd/compare -threshold 0.1 baseline.csv cached.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+)
+
+func main() {
+	threshold := flag.Float64("threshold", 0.1, "relative change treated as regression, 0.1 is 10% worse")
+	alpha := flag.Float64("alpha", 0.05, "significance level")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: compare [flags] baseline.csv candidate.csv [candidate.jsonl...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	base, err := metrics.ReadRunLog(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := metrics.CompareOptions{
+		Threshold: *threshold,
+		Alpha:     *alpha,
+	}
+
+	regression := false
+	for _, path := range flag.Args()[1:] {
+		candidate, err := metrics.ReadRunLog(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		comparisons := metrics.Compare(base, candidate, opts)
+		if err := metrics.WriteComparison(os.Stdout, base.Name, candidate.Name, comparisons); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println()
+
+		for _, c := range comparisons {
+			regression = regression || c.Regression
+		}
+	}
+
+	if regression {
+		fmt.Printf("regression above %.1f%% found\n", *threshold*100)
+		os.Exit(1)
+	}
+}
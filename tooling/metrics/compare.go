@@ -0,0 +1,368 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvLegacyWindow is the window length of CSV logs before schema v4, their rows don't carry window boundaries
+const csvLegacyWindow = time.Second
+
+// RunLog is per-window samples of every span key read from a metrics log
+type RunLog struct {
+	Name string
+	Keys map[string]*KeySeries
+	// ends is the last read window end of every key, rows of sliding windows overlapping it are skipped
+	ends map[string]time.Time
+}
+
+// KeySeries has one value per window, latencies are in milliseconds
+type KeySeries struct {
+	Count int64
+	RPS   []float64
+	P50   []float64
+	P90   []float64
+	P99   []float64
+	// RespP99 is p99 of response time from intended start
+	RespP99 []float64
+}
+
+// CompareMetrics are per-window series compared by Compare
+var CompareMetrics = []string{"rps", "p50", "p90", "p99", "resp_p99"}
+
+func (s *KeySeries) metric(name string) []float64 {
+	switch name {
+	case "rps":
+		return s.RPS
+	case "p50":
+		return s.P50
+	case "p90":
+		return s.P90
+	case "p99":
+		return s.P99
+	case "resp_p99":
+		return s.RespP99
+	}
+
+	return nil
+}
+
+func seriesKey(key, attrs string) string {
+	if attrs == "" {
+		return key
+	}
+
+	return key + "{" + attrs + "}"
+}
+
+// overlaps reports whether the window overlaps the previous one read for the key. Sliding window rows
+// share spans with their neighbours, so only every n-th of them is taken to keep samples independent.
+func (l *RunLog) overlaps(key string, start, end time.Time) bool {
+	if start.Before(l.ends[key]) {
+		return true
+	}
+
+	l.ends[key] = end
+
+	return false
+}
+
+func (l *RunLog) add(key string, count int64, window time.Duration, l50, l90, l99, r99 time.Duration) {
+	s, ok := l.Keys[key]
+	if !ok {
+		s = &KeySeries{}
+		l.Keys[key] = s
+	}
+
+	ms := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+
+	s.Count += count
+	s.RPS = append(s.RPS, rate(count, window))
+
+	if count > 0 {
+		s.P50 = append(s.P50, ms(l50))
+		s.P90 = append(s.P90, ms(l90))
+		s.P99 = append(s.P99, ms(l99))
+		s.RespP99 = append(s.RespP99, ms(r99))
+	}
+}
+
+// ReadRunLog reads a CSV or JSON Lines (.jsonl, .json) metrics log, only not overlapping rows of sliding windows are kept
+func ReadRunLog(path string) (*RunLog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %v", err)
+	}
+	defer f.Close()
+
+	l := &RunLog{
+		Name: filepath.Base(path),
+		Keys: map[string]*KeySeries{},
+		ends: map[string]time.Time{},
+	}
+
+	switch filepath.Ext(path) {
+	case ".jsonl", ".json":
+		err = l.readJSONL(f)
+	default:
+		err = l.readCSV(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	return l, nil
+}
+
+func (l *RunLog) readCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[name] = i
+	}
+
+	for _, name := range []string{"key", "attrs", "count", "p50_ns", "p90_ns", "p99_ns"} {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("no %s column, only logs with header are supported", name)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if kind, ok := columns["kind"]; ok && record[kind] != csvKindSpan {
+			continue
+		}
+
+		values := map[string]int64{}
+		for _, name := range []string{"count", "p50_ns", "p90_ns", "p99_ns", "resp_p99_ns"} {
+			i, ok := columns[name]
+			if !ok {
+				// schema v1 logs have no response time, it equals service time there
+				values[name] = values["p99_ns"]
+				continue
+			}
+
+			v, err := strconv.ParseInt(record[i], 10, 64)
+			if err != nil {
+				return fmt.Errorf("bad %s value: %v", name, err)
+			}
+			values[name] = v
+		}
+
+		start, end, err := csvRecordWindow(columns, record)
+		if err != nil {
+			return err
+		}
+
+		window := end.Sub(start)
+		if end.IsZero() {
+			window = csvLegacyWindow
+		}
+
+		key := seriesKey(record[columns["key"]], record[columns["attrs"]])
+		if l.overlaps(key, start, end) {
+			continue
+		}
+
+		l.add(
+			key,
+			values["count"], window,
+			time.Duration(values["p50_ns"]), time.Duration(values["p90_ns"]), time.Duration(values["p99_ns"]),
+			time.Duration(values["resp_p99_ns"]),
+		)
+	}
+}
+
+// csvRecordWindow is the window boundaries from window_start and window_end columns, zero for legacy logs
+func csvRecordWindow(columns map[string]int, record []string) (time.Time, time.Time, error) {
+	startColumn, hasStart := columns["window_start"]
+	endColumn, hasEnd := columns["window_end"]
+	if !hasStart || !hasEnd {
+		return time.Time{}, time.Time{}, nil
+	}
+
+	start, err := strconv.ParseInt(record[startColumn], 10, 64)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("bad window_start value: %v", err)
+	}
+
+	end, err := strconv.ParseInt(record[endColumn], 10, 64)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("bad window_end value: %v", err)
+	}
+
+	return time.UnixMilli(start), time.UnixMilli(end), nil
+}
+
+func (l *RunLog) readJSONL(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		var w windowJSON
+		if err := json.Unmarshal(scanner.Bytes(), &w); err != nil {
+			return err
+		}
+
+		for _, s := range w.Spans {
+			key := seriesKey(s.Key, formatAttrs(s.Attrs))
+			if l.overlaps(key, w.Start, w.Time) {
+				continue
+			}
+
+			l.add(
+				key,
+				s.Count, w.Time.Sub(w.Start),
+				s.Latency.P50, s.Latency.P90, s.Latency.P99, s.Response.P99,
+			)
+		}
+	}
+
+	return scanner.Err()
+}
+
+type CompareOptions struct {
+	// Threshold is the relative change treated as regression, 0.1 is 10% worse
+	Threshold float64
+	// Alpha is significance level of the change
+	Alpha float64
+}
+
+// Comparison is a change of one metric of one span key between base and candidate runs
+type Comparison struct {
+	Key           string
+	Metric        string
+	Base          float64
+	Candidate     float64
+	BaseWindows   int
+	CandWindows   int
+	Delta         float64
+	DeltaLow      float64
+	DeltaHigh     float64
+	PValue        float64
+	Significant   bool
+	Regression    bool
+	MissingInBase bool
+	MissingInCand bool
+}
+
+// Compare runs Welch's t-test over per-window values of every metric.
+// Delta and its confidence interval are relative to the base mean. Lower RPS and higher latency are regressions.
+func Compare(base, candidate *RunLog, opts CompareOptions) []Comparison {
+	keys := map[string]bool{}
+	for k := range base.Keys {
+		keys[k] = true
+	}
+	for k := range candidate.Keys {
+		keys[k] = true
+	}
+
+	var result []Comparison
+	for _, key := range slices.Sorted(maps.Keys(keys)) {
+		baseSeries, inBase := base.Keys[key]
+		candSeries, inCand := candidate.Keys[key]
+
+		if !inBase || !inCand {
+			result = append(result, Comparison{Key: key, MissingInBase: !inBase, MissingInCand: !inCand})
+			continue
+		}
+
+		for _, metric := range CompareMetrics {
+			b, c := newSampleStats(baseSeries.metric(metric)), newSampleStats(candSeries.metric(metric))
+			diff, low, high, p := welchTest(b, c, 1-opts.Alpha)
+
+			cmp := Comparison{
+				Key:         key,
+				Metric:      metric,
+				Base:        b.mean,
+				Candidate:   c.mean,
+				BaseWindows: b.n,
+				CandWindows: c.n,
+				Delta:       relative(diff, b.mean),
+				DeltaLow:    relative(low, b.mean),
+				DeltaHigh:   relative(high, b.mean),
+				PValue:      p,
+				Significant: p < opts.Alpha,
+			}
+
+			worse := cmp.Delta
+			if metric == "rps" {
+				worse = -worse
+			}
+			cmp.Regression = cmp.Significant && worse > opts.Threshold
+
+			result = append(result, cmp)
+		}
+	}
+
+	return result
+}
+
+func relative(v, base float64) float64 {
+	if base == 0 {
+		return math.NaN()
+	}
+
+	return v / base
+}
+
+// WriteComparison prints comparisons as an aligned text table
+func WriteComparison(w io.Writer, base, candidate string, comparisons []Comparison) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s vs %s\n", candidate, base)
+	fmt.Fprintf(&b, "%-50s %-6s %12s %12s %9s %21s %9s\n", "key", "metric", "base", "candidate", "delta", "ci", "p")
+
+	for _, c := range comparisons {
+		if c.MissingInBase || c.MissingInCand {
+			where := "base"
+			if c.MissingInCand {
+				where = "candidate"
+			}
+			fmt.Fprintf(&b, "%-50s missing in %s\n", c.Key, where)
+			continue
+		}
+
+		mark := ""
+		if c.Regression {
+			mark = "  REGRESSION"
+		} else if c.Significant {
+			mark = "  *"
+		}
+
+		fmt.Fprintf(&b, "%-50s %-6s %12.3f %12.3f %+8.1f%% [%+8.1f%%, %+8.1f%%] %9.4f%s\n",
+			c.Key, c.Metric, c.Base, c.Candidate,
+			c.Delta*100, c.DeltaLow*100, c.DeltaHigh*100, c.PValue, mark)
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
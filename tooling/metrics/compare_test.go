@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRunLog(t *testing.T, path string, sink Sink, latency func(i int) time.Duration) {
+	start := time.Unix(1_700_000_000, 0)
+
+	for i := range 30 {
+		ws := start.Add(time.Duration(i) * time.Second)
+
+		var results []result
+		for j := range 50 {
+			results = append(results, result{key: "UserDashboard", start: ws, duration: latency(i*50 + j)})
+		}
+
+		require.NoError(t, sink.Write(newWindow(results, newDimensions(defaultAttrSetsLimit), nil, 0, ws, ws.Add(time.Second))))
+	}
+
+	require.NoError(t, sink.Close())
+	require.FileExists(t, path)
+}
+
+func TestCompare(t *testing.T) {
+	dir := t.TempDir()
+
+	create := func(name string) *os.File {
+		f, err := os.Create(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return f
+	}
+
+	baseline := func(i int) time.Duration { return time.Duration(10+i%7) * time.Millisecond }
+	slower := func(i int) time.Duration { return time.Duration(20+i%7) * time.Millisecond }
+
+	writeRunLog(t, filepath.Join(dir, "base.csv"), NewCSVSink(create("base.csv")), baseline)
+	writeRunLog(t, filepath.Join(dir, "same.jsonl"), NewJSONLSink(create("same.jsonl")), baseline)
+	writeRunLog(t, filepath.Join(dir, "slow.csv"), NewCSVSink(create("slow.csv")), slower)
+
+	base, err := ReadRunLog(filepath.Join(dir, "base.csv"))
+	require.NoError(t, err)
+	same, err := ReadRunLog(filepath.Join(dir, "same.jsonl"))
+	require.NoError(t, err)
+	slow, err := ReadRunLog(filepath.Join(dir, "slow.csv"))
+	require.NoError(t, err)
+
+	opts := CompareOptions{Threshold: 0.1, Alpha: 0.05}
+
+	for _, c := range Compare(base, same, opts) {
+		assert.False(t, c.Regression, c.Metric)
+		assert.InDelta(t, 0, c.Delta, 1e-9, c.Metric)
+	}
+
+	regressions := map[string]bool{}
+	for _, c := range Compare(base, slow, opts) {
+		regressions[c.Metric] = c.Regression
+	}
+	assert.Equal(t, map[string]bool{"rps": false, "p50": true, "p90": true, "p99": true, "resp_p99": true}, regressions)
+}
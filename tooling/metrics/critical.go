@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+)
+
+const selfBound = "(self)"
+
+type BoundBy struct {
+	Child string
+	Count int
+	Share float64
+}
+
+// CriticalPathStat tells which child spans the slow requests of a root span were waiting for
+type CriticalPathStat struct {
+	Root      string
+	Quantile  float64
+	Threshold time.Duration
+	Total     int
+	Slow      int
+	BoundBy   []BoundBy
+}
+
+func (s CriticalPathStat) String() string {
+	if len(s.BoundBy) == 0 {
+		return fmt.Sprintf("no slow %s traces", s.Root)
+	}
+
+	top := s.BoundBy[0]
+
+	return fmt.Sprintf("%.0f%% of slow %s (>= p%g %v, %d of %d) were bound by %s",
+		top.Share*100, s.Root, s.Quantile*100, s.Threshold, s.Slow, s.Total, top.Child)
+}
+
+// AnalyzeCriticalPath picks traces with root duration at or above the quantile
+// and counts which direct child dominated each of them.
+func AnalyzeCriticalPath(traces []Trace, quantile float64) []CriticalPathStat {
+	byRoot := map[string][]*Trace{}
+	for i := range traces {
+		root := traces[i].Root()
+		byRoot[root.Name] = append(byRoot[root.Name], &traces[i])
+	}
+
+	var stats []CriticalPathStat
+	for _, name := range slices.Sorted(maps.Keys(byRoot)) {
+		group := byRoot[name]
+
+		h := NewHistogram()
+		for _, t := range group {
+			h.Record(t.Root().Duration)
+		}
+
+		stat := CriticalPathStat{
+			Root:      name,
+			Quantile:  quantile,
+			Threshold: h.Quantile(quantile),
+			Total:     len(group),
+		}
+
+		counts := map[string]int{}
+		for _, t := range group {
+			if t.Root().Duration < stat.Threshold {
+				continue
+			}
+
+			stat.Slow++
+
+			child := selfBound
+			if path := t.CriticalPath(); len(path) > 1 {
+				child = path[1].Name
+			}
+			counts[child]++
+		}
+
+		for child, count := range counts {
+			stat.BoundBy = append(stat.BoundBy, BoundBy{
+				Child: child,
+				Count: count,
+				Share: float64(count) / float64(stat.Slow),
+			})
+		}
+
+		slices.SortFunc(stat.BoundBy, func(a, b BoundBy) int {
+			return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Child, b.Child))
+		})
+
+		stats = append(stats, stat)
+	}
+
+	return stats
+}
+
+// ReadTraces reads trace records written with WithTraceWriter
+func ReadTraces(r io.Reader) ([]Trace, error) {
+	var traces []Trace
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var t Trace
+		if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+			return nil, fmt.Errorf("error decoding trace: %v", err)
+		}
+		traces = append(traces, t)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading traces: %v", err)
+	}
+
+	return traces, nil
+}
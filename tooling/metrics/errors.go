@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+type errorClass int
+
+const (
+	errDeadline errorClass = iota
+	errCanceled
+	errSQL
+	errOther
+	errClassesCount
+)
+
+// pq reports statements canceled by context as a server error with this code
+const pqQueryCanceled = "57014"
+
+var errorClassNames = [errClassesCount]string{
+	errDeadline: "deadline",
+	errCanceled: "canceled",
+	errSQL:      "sql",
+	errOther:    "other",
+}
+
+func (c errorClass) String() string {
+	return errorClassNames[c]
+}
+
+func classifyError(err error) errorClass {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errDeadline
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return errCanceled
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if pqErr.Code == pqQueryCanceled {
+			return errCanceled
+		}
+
+		return errSQL
+	}
+
+	if errors.Is(err, sql.ErrNoRows) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, sql.ErrTxDone) ||
+		errors.Is(err, driver.ErrBadConn) {
+		return errSQL
+	}
+
+	return errOther
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err   error
+		class errorClass
+	}{
+		{context.DeadlineExceeded, errDeadline},
+		{fmt.Errorf("error: %w", context.DeadlineExceeded), errDeadline},
+		{context.Canceled, errCanceled},
+		{&pq.Error{Code: pqQueryCanceled}, errCanceled},
+		{&pq.Error{Code: "42P01"}, errSQL},
+		{fmt.Errorf("scan: %w", sql.ErrNoRows), errSQL},
+		{errors.New("boom"), errOther},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.class, classifyError(c.err), c.err.Error())
+	}
+}
+
+func TestNewLogsCountsErrors(t *testing.T) {
+	var results []result
+	for i := 0; i < 100; i++ {
+		var err error
+		switch {
+		case i < 5:
+			err = context.DeadlineExceeded
+		case i < 7:
+			err = &pq.Error{Code: "42P01"}
+		}
+
+		results = append(results, result{key: "op", start: time.UnixMilli(1000), duration: 1, err: err})
+	}
+
+	w := newWindow(results, newDimensions(defaultAttrSetsLimit), nil, 0, time.Now(), time.Now())
+	require.Len(t, w.Spans, 1)
+
+	stats := w.Spans[0]
+	assert.Equal(t, int64(100), stats.Count)
+	assert.Equal(t, int64(93), stats.Success)
+	assert.Equal(t, int64(7), stats.Errors)
+	assert.InDelta(t, 0.07, stats.ErrorRate(), 1e-9)
+	assert.Equal(t, int64(5), stats.Deadline)
+	assert.Equal(t, int64(2), stats.SQL)
+	assert.Zero(t, stats.Canceled+stats.Other)
+}
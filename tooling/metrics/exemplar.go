@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const defaultExemplars = 5
+
+// Exemplar is one of the slowest spans of a window with its arguments, so the request can be reproduced
+type Exemplar struct {
+	TraceID  TraceID       `json:"trace_id"`
+	SpanID   SpanID        `json:"span_id"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration_ns"`
+	Response time.Duration `json:"response_ns"`
+	Err      string        `json:"error,omitempty"`
+	Args     []Attr        `json:"args,omitempty"`
+}
+
+type exemplarJSON struct {
+	SchemaVersion int       `json:"schema_version"`
+	WindowStart   time.Time `json:"window_start"`
+	WindowEnd     time.Time `json:"window_end"`
+	Key           string    `json:"key"`
+	Attrs         []Attr    `json:"attrs,omitempty"`
+	// Rank is 1 for the slowest span of the window
+	Rank int `json:"rank"`
+	Exemplar
+}
+
+// ExemplarSink writes one JSON object per exemplar, windows without exemplars write nothing.
+// Sliding windows repeat exemplars of their steps, only the latest step is written, so every exemplar appears once.
+type ExemplarSink struct {
+	file    io.Writer
+	encoder *json.Encoder
+}
+
+func NewExemplarSink(w io.Writer) *ExemplarSink {
+	return &ExemplarSink{
+		file:    w,
+		encoder: json.NewEncoder(w),
+	}
+}
+
+func (s *ExemplarSink) Write(w *Window) error {
+	if w.latest != nil {
+		w = w.latest
+	}
+
+	for _, stats := range w.Spans {
+		for i, e := range stats.Exemplars {
+			err := s.encoder.Encode(exemplarJSON{
+				SchemaVersion: SchemaVersion,
+				WindowStart:   w.Start,
+				WindowEnd:     w.Time,
+				Key:           stats.Key,
+				Attrs:         stats.Attrs,
+				Rank:          i + 1,
+				Exemplar:      e,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write exemplar: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *ExemplarSink) Close() error {
+	return closeWriter(s.file)
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExemplarsKeepSlowest(t *testing.T) {
+	start := time.Now()
+
+	var results []result
+	for i, d := range []time.Duration{3, 9, 1, 7, 5} {
+		results = append(results, result{
+			key:      "UserDashboard",
+			start:    start,
+			duration: d * time.Millisecond,
+			args:     []Attr{Int("user_id", i)},
+		})
+	}
+	results[1].err = errors.New("boom")
+
+	w := newWindow(results, newDimensions(defaultAttrSetsLimit), nil, 2, start, start.Add(time.Second))
+	require.Len(t, w.Spans, 1)
+
+	exemplars := w.Spans[0].Exemplars
+	require.Len(t, exemplars, 2)
+	assert.Equal(t, 9*time.Millisecond, exemplars[0].Duration)
+	assert.Equal(t, []Attr{Int("user_id", 1)}, exemplars[0].Args)
+	assert.Equal(t, "boom", exemplars[0].Err)
+	assert.Equal(t, 7*time.Millisecond, exemplars[1].Duration)
+
+	total := newSpanStats("UserDashboard", nil, start)
+	total.Merge(w.Spans[0])
+	total.Merge(&SpanStats{
+		Latency:   NewHistogram(),
+		Response:  NewHistogram(),
+		Exemplars: []Exemplar{{Duration: 8 * time.Millisecond}},
+	})
+
+	require.Len(t, total.Exemplars, 2)
+	assert.Equal(t, 9*time.Millisecond, total.Exemplars[0].Duration)
+	assert.Equal(t, 8*time.Millisecond, total.Exemplars[1].Duration)
+}
+
+func TestExemplarSink(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	o := New(nil, WithExemplars(1), WithSinks(NewExemplarSink(out)))
+	o.StartLogging(context.Background())
+
+	s := o.StartSpan("UserDashboard", String("page", "1"))
+	s.SetArgs(Int("user_id", 42))
+	s.Done(nil)
+
+	require.NoError(t, o.Close())
+
+	lines := bufio.NewScanner(out)
+	require.True(t, lines.Scan())
+
+	var e exemplarJSON
+	require.NoError(t, json.Unmarshal(lines.Bytes(), &e))
+	assert.Equal(t, "UserDashboard", e.Key)
+	assert.Equal(t, []Attr{String("page", "1")}, e.Attrs)
+	assert.Equal(t, []Attr{Int("user_id", 42)}, e.Args)
+	assert.Equal(t, 1, e.Rank)
+	assert.False(t, e.SpanID.IsZero())
+
+	assert.False(t, lines.Scan())
+}
+
+func TestExemplarSinkSlidingWindow(t *testing.T) {
+	out := &bytes.Buffer{}
+	sink := NewExemplarSink(out)
+
+	start := time.Unix(1_700_000_000, 0)
+	dims := newDimensions(defaultAttrSetsLimit)
+
+	var steps []*Window
+	for i := range 4 {
+		ws := start.Add(time.Duration(i) * time.Second)
+		results := []result{{key: "UserDashboard", start: ws, duration: time.Millisecond, args: []Attr{Int("user_id", i)}}}
+
+		steps = append(steps, newWindow(results, dims, nil, 1, ws, ws.Add(time.Second)))
+		require.NoError(t, sink.Write(slide(steps[max(i-2, 0):])))
+	}
+
+	seen := map[string]int{}
+	lines := bufio.NewScanner(out)
+	for lines.Scan() {
+		var e exemplarJSON
+		require.NoError(t, json.Unmarshal(lines.Bytes(), &e))
+		assert.Equal(t, time.Second, e.WindowEnd.Sub(e.WindowStart))
+		seen[formatAttrs(e.Args)]++
+	}
+
+	// every step has one exemplar, overlapping windows don't repeat it
+	assert.Equal(t, map[string]int{"user_id=0": 1, "user_id=1": 1, "user_id=2": 1, "user_id=3": 1}, seen)
+}
@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Log-linear buckets: values below subBucketCount are exact, above that every
+// power of two is split into subBucketCount linear buckets, so the relative
+// error stays under 1/subBucketCount and the bucket count is bounded by 64 bits.
+const (
+	subBucketBits  = 6
+	subBucketCount = 1 << subBucketBits
+	maxBuckets     = (64-subBucketBits)*subBucketCount + subBucketCount
+)
+
+// Histogram is a mergeable latency histogram with bounded memory.
+type Histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+	min    uint64
+	max    uint64
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{}
+}
+
+func bucketIndex(v uint64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+
+	shift := bits.Len64(v) - subBucketBits - 1
+
+	return (shift+1)*subBucketCount + int(v>>shift) - subBucketCount
+}
+
+func bucketUpperBound(idx int) uint64 {
+	if idx < subBucketCount {
+		return uint64(idx)
+	}
+
+	shift := idx/subBucketCount - 1
+	mantissa := uint64(idx%subBucketCount + subBucketCount)
+
+	return (mantissa+1)<<shift - 1
+}
+
+func (h *Histogram) Record(d time.Duration) {
+	h.RecordN(d, 1)
+}
+
+func (h *Histogram) RecordN(d time.Duration, n uint64) {
+	if n == 0 {
+		return
+	}
+
+	v := uint64(max(d, 0))
+
+	idx := bucketIndex(v)
+	if idx >= len(h.counts) {
+		h.grow(idx + 1)
+	}
+
+	h.counts[idx] += n
+
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+
+	h.count += n
+	h.sum += float64(v) * float64(n)
+}
+
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil || other.count == 0 {
+		return
+	}
+
+	if len(other.counts) > len(h.counts) {
+		h.grow(len(other.counts))
+	}
+
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+
+	h.count += other.count
+	h.sum += other.sum
+}
+
+func (h *Histogram) grow(size int) {
+	counts := make([]uint64, min(size, maxBuckets))
+	copy(counts, h.counts)
+	h.counts = counts
+}
+
+func (h *Histogram) Count() uint64 {
+	return h.count
+}
+
+func (h *Histogram) Min() time.Duration {
+	return time.Duration(h.min)
+}
+
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max)
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	return time.Duration(h.sum / float64(h.count))
+}
+
+// Quantile returns the upper bound of the bucket holding the q-quantile sample.
+// Result precision is limited by bucket width, but it never leaves the [min, max] range.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	rank = max(rank, 1)
+
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			v := min(max(bucketUpperBound(i), h.min), h.max)
+
+			return time.Duration(v)
+		}
+	}
+
+	return time.Duration(h.max)
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketIndexIsMonotonic(t *testing.T) {
+	prev := -1
+	for _, v := range []uint64{0, 1, 63, 64, 65, 127, 128, 129, 1000, 1 << 20, 1<<40 + 7, 1<<63 + 1} {
+		idx := bucketIndex(v)
+		require.GreaterOrEqual(t, idx, prev, v)
+		require.Less(t, idx, maxBuckets, v)
+		require.GreaterOrEqual(t, bucketUpperBound(idx), v, v)
+		prev = idx
+	}
+}
+
+func TestHistogramSmallWindow(t *testing.T) {
+	h := NewHistogram()
+	h.Record(42 * time.Millisecond)
+
+	for _, q := range []float64{0.5, 0.99, 0.999} {
+		assert.Equal(t, 42*time.Millisecond, h.Quantile(q))
+	}
+	assert.Equal(t, 42*time.Millisecond, h.Max())
+	assert.Equal(t, 42*time.Millisecond, h.Mean())
+
+	assert.Zero(t, NewHistogram().Quantile(0.99))
+}
+
+func TestHistogramNegativeDuration(t *testing.T) {
+	h := NewHistogram()
+	h.Record(-time.Second)
+
+	assert.Equal(t, time.Duration(0), h.Max())
+}
+
+func TestHistogramQuantilePrecision(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+
+	h := NewHistogram()
+	values := make([]time.Duration, 10_000)
+	for i := range values {
+		values[i] = time.Duration(r.ExpFloat64() * float64(10*time.Millisecond))
+		h.Record(values[i])
+	}
+	slices.Sort(values)
+
+	for _, q := range []float64{0.5, 0.9, 0.95, 0.99, 0.999} {
+		exact := values[int(q*float64(len(values)))-1]
+		assert.InEpsilon(t, float64(exact), float64(h.Quantile(q)), 1.0/subBucketCount*2, q)
+	}
+	assert.Equal(t, values[len(values)-1], h.Max())
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a, b, total := NewHistogram(), NewHistogram(), NewHistogram()
+	for i := 1; i <= 1000; i++ {
+		d := time.Duration(i) * time.Microsecond
+		if i%2 == 0 {
+			a.Record(d)
+		} else {
+			b.Record(d)
+		}
+		total.Record(d)
+	}
+
+	a.Merge(b)
+
+	assert.Equal(t, total.Count(), a.Count())
+	assert.Equal(t, total.Min(), a.Min())
+	assert.Equal(t, total.Max(), a.Max())
+	assert.Equal(t, total.Mean(), a.Mean())
+	assert.Equal(t, total.Quantile(0.99), a.Quantile(0.99))
+}
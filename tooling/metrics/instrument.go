@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// CounterValue is the increase of a counter within the window
+type CounterValue struct {
+	Name  string `json:"name"`
+	Attrs []Attr `json:"attrs,omitempty"`
+	Value int64  `json:"value"`
+}
+
+// instruments keeps counters and gauges set through Obs between windows.
+// Attribute sets are limited per name the same way as for spans.
+type instruments struct {
+	mu       sync.Mutex
+	dims     *dimensions
+	counters map[dimensionKey]*CounterValue
+	gauges   map[dimensionKey]*GaugeValue
+}
+
+func newInstruments(attrSets int) *instruments {
+	return &instruments{
+		dims:     newDimensions(attrSets),
+		counters: map[dimensionKey]*CounterValue{},
+		gauges:   map[dimensionKey]*GaugeValue{},
+	}
+}
+
+func (i *instruments) add(name string, delta int64, attrs []Attr) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	sorted, key := i.dims.attrs(name, attrs)
+	k := dimensionKey{key: name, attrs: key}
+
+	c, ok := i.counters[k]
+	if !ok {
+		c = &CounterValue{Name: name, Attrs: sorted}
+		i.counters[k] = c
+	}
+
+	c.Value += delta
+}
+
+func (i *instruments) set(name string, value float64, attrs []Attr) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	sorted, key := i.dims.attrs(name, attrs)
+	k := dimensionKey{key: name, attrs: key}
+
+	g, ok := i.gauges[k]
+	if !ok {
+		g = &GaugeValue{Name: name, Attrs: sorted}
+		i.gauges[k] = g
+	}
+
+	g.Value = value
+}
+
+// snapshot returns every known counter with its increase since the last call, zero included,
+// and the last value of every gauge
+func (i *instruments) snapshot() ([]CounterValue, []GaugeValue) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	counters := make([]CounterValue, 0, len(i.counters))
+	for _, c := range i.counters {
+		counters = append(counters, *c)
+		c.Value = 0
+	}
+
+	gauges := make([]GaugeValue, 0, len(i.gauges))
+	for _, g := range i.gauges {
+		gauges = append(gauges, *g)
+	}
+
+	slices.SortFunc(counters, func(a, b CounterValue) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(formatAttrs(a.Attrs), formatAttrs(b.Attrs)))
+	})
+	slices.SortFunc(gauges, func(a, b GaugeValue) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(formatAttrs(a.Attrs), formatAttrs(b.Attrs)))
+	})
+
+	return counters, gauges
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountersAndGauges(t *testing.T) {
+	logs := &bytes.Buffer{}
+	summary := NewSummarySink(&bytes.Buffer{}, nil)
+
+	o := New(logs, WithSinks(summary), WithAttrSetsLimit(2))
+	o.StartLogging(context.Background())
+
+	o.Add("cache.requests", 2, String("result", "hit"))
+	o.Add("cache.requests", 1, String("result", "miss"))
+	o.Add("cache.requests", 1, String("result", "error"))
+	o.SetGauge("cache.size_bytes", 100)
+	o.SetGauge("cache.size_bytes", 200)
+
+	require.NoError(t, o.Close())
+
+	var counters, gauges []map[string]string
+	for _, row := range readCSVRows(t, logs.String()) {
+		switch row["kind"] {
+		case csvKindCounter:
+			counters = append(counters, row)
+		case csvKindGauge:
+			gauges = append(gauges, row)
+		}
+	}
+
+	require.Len(t, counters, 3)
+	// the third attribute set is over the limit
+	assert.Equal(t, "overflow=true", counters[0]["attrs"])
+	assert.Equal(t, "result=hit", counters[1]["attrs"])
+	assert.Equal(t, "2", counters[1]["value"])
+	assert.Equal(t, "result=miss", counters[2]["attrs"])
+
+	require.Len(t, gauges, 1)
+	assert.Equal(t, "cache.size_bytes", gauges[0]["key"])
+	assert.Equal(t, "200", gauges[0]["value"])
+
+	require.Len(t, summary.Summary().Counters, 3)
+	assert.Equal(t, int64(2), summary.Summary().Counters[1].Total)
+}
+
+func TestCountersReportZeroIncrease(t *testing.T) {
+	i := newInstruments(defaultAttrSetsLimit)
+	i.add("rows.scanned", 10, nil)
+
+	counters, _ := i.snapshot()
+	assert.Equal(t, []CounterValue{{Name: "rows.scanned", Value: 10}}, counters)
+
+	counters, _ = i.snapshot()
+	assert.Equal(t, []CounterValue{{Name: "rows.scanned", Value: 0}}, counters)
+}
+
+func TestSlidingWindowSumsCounters(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+
+	steps := []*Window{
+		{Start: start, Time: start.Add(time.Second), Counters: []CounterValue{{Name: "hits", Value: 1}}},
+		{Start: start.Add(time.Second), Time: start.Add(2 * time.Second), Counters: []CounterValue{{Name: "hits", Value: 2}}},
+	}
+
+	w := slide(steps)
+	assert.Equal(t, []CounterValue{{Name: "hits", Value: 3}}, w.Counters)
+
+	md := &bytes.Buffer{}
+	summary := NewSummary()
+	summary.Add(slide(steps[:1]))
+	summary.Add(w)
+	require.NoError(t, summary.WriteMarkdown(md))
+
+	assert.Contains(t, md.String(), "## Counters")
+	assert.Contains(t, md.String(), "| hits |  | 3 | 1.5 |")
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"context"
+	"time"
+)
+
+type intendedStartKey struct{}
+
+// WithIntendedStart passes the moment a load generator scheduled the request.
+// The next span started with the context measures response time from it, not only its own service time,
+// so requests queued behind a stalled one aren't reported as fast (coordinated omission).
+func WithIntendedStart(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, intendedStartKey{}, t)
+}
+
+// IntendedStart returns the intended start set for the next span
+func IntendedStart(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(intendedStartKey{}).(time.Time)
+
+	return t, ok && !t.IsZero()
+}
+
+// ConsumeIntendedStart hides intended start from child spans, they report only service time.
+// Obs implementations call it on the context they return from Start.
+func ConsumeIntendedStart(ctx context.Context) context.Context {
+	if _, ok := IntendedStart(ctx); !ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, intendedStartKey{}, time.Time{})
+}
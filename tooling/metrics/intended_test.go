@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntendedStart(t *testing.T) {
+	o := New(&bytes.Buffer{})
+
+	ctx := WithIntendedStart(context.Background(), time.Now().Add(-100*time.Millisecond))
+
+	ctx, root := o.Start(ctx, "UserDashboard")
+	_, child := o.Start(ctx, "GetArticleFeed")
+	child.Done(nil)
+	root.Done(nil)
+
+	results := []result{<-o.metricsChan, <-o.metricsChan}
+	w := newWindow(results, newDimensions(defaultAttrSetsLimit), nil, 0, time.Now(), time.Now())
+	require.Len(t, w.Spans, 2)
+
+	feed, dashboard := w.Spans[0], w.Spans[1]
+
+	assert.Less(t, dashboard.Latency.Max(), 50*time.Millisecond)
+	assert.GreaterOrEqual(t, dashboard.Response.Max(), 100*time.Millisecond)
+
+	assert.Equal(t, feed.Latency.Max(), feed.Response.Max(), "children report service time only")
+}
+
+func TestIntendedStartWithMulti(t *testing.T) {
+	a, b := New(&bytes.Buffer{}), New(&bytes.Buffer{})
+
+	ctx := WithIntendedStart(context.Background(), time.Now().Add(-time.Second))
+	ctx, span := Multi(a, b).Start(ctx, "UserDashboard")
+	span.Done(nil)
+
+	_, ok := IntendedStart(ctx)
+	assert.False(t, ok)
+
+	for _, o := range []*Observability{a, b} {
+		r := <-o.metricsChan
+		assert.GreaterOrEqual(t, r.response(), time.Second)
+	}
+}
@@ -1,9 +1,23 @@
 package metrics
 
+import "context"
+
 type Span interface {
+	// SetAttributes adds dimensions known only after start, e.g. cache=hit
+	SetAttributes(attrs ...Attr)
+	// SetArgs records request arguments, e.g. user_id. Unlike attributes they aren't aggregated,
+	// only kept on traces and exemplars of the slowest spans.
+	SetArgs(args ...Attr)
 	Done(err error)
 }
 
 type Obs interface {
-	StartSpan(name string) (span Span)
+	// StartSpan starts a new root span, same as Start with an empty context
+	StartSpan(name string, attrs ...Attr) (span Span)
+	// Start starts a child of the span carried by ctx and returns a context carrying the new span
+	Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span)
+	// Add increases a counter, e.g. cache hits or rows scanned. Windows report the increase within them.
+	Add(name string, delta int64, attrs ...Attr)
+	// SetGauge sets a current value, e.g. cache size or queue depth. Windows report the last value.
+	SetGauge(name string, value float64, attrs ...Attr)
 }
@@ -0,0 +1,376 @@
+// Package metricstest provides an in-memory metrics.Obs for unit tests of instrumented code.
+package metricstest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+)
+
+type spanContextKey struct{}
+
+// Span is a recorded span, Parent is nil for roots
+type Span struct {
+	Name     string
+	Attrs    []metrics.Attr
+	Args     []metrics.Attr
+	Parent   *Span
+	Start    time.Time
+	Intended time.Time
+	End      time.Time
+	Err      error
+	Finished bool
+
+	recorder *Recorder
+}
+
+func (s *Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Attr returns the last value set for key
+func (s *Span) Attr(key string) (string, bool) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	return lastValue(s.Attrs, key)
+}
+
+// Arg returns the last argument value set for key
+func (s *Span) Arg(key string) (string, bool) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	return lastValue(s.Args, key)
+}
+
+func lastValue(attrs []metrics.Attr, key string) (string, bool) {
+	for i := len(attrs) - 1; i >= 0; i-- {
+		if attrs[i].Key == key {
+			return attrs[i].Value, true
+		}
+	}
+
+	return "", false
+}
+
+func (s *Span) SetAttributes(attrs ...metrics.Attr) {
+	s.recorder.mu.Lock()
+	s.Attrs = append(s.Attrs, attrs...)
+	s.recorder.mu.Unlock()
+}
+
+func (s *Span) SetArgs(args ...metrics.Attr) {
+	s.recorder.mu.Lock()
+	s.Args = append(s.Args, args...)
+	s.recorder.mu.Unlock()
+}
+
+func (s *Span) Done(err error) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	if s.Finished {
+		s.recorder.doubleDone = append(s.recorder.doubleDone, s.Name)
+		return
+	}
+
+	s.End = time.Now()
+	s.Err = err
+	s.Finished = true
+}
+
+// Recorder is a metrics.Obs keeping every span, counter increase and gauge value in memory, safe for concurrent use
+type Recorder struct {
+	mu         sync.Mutex
+	spans      []*Span
+	doubleDone []string
+	counters   []Value
+	gauges     []Value
+}
+
+// Value is one recorded counter increase or gauge value
+type Value struct {
+	Name  string
+	Attrs []metrics.Attr
+	Value float64
+}
+
+// matches checks the value has every given attribute
+func (v Value) matches(name string, attrs []metrics.Attr) bool {
+	if v.Name != name {
+		return false
+	}
+
+	for _, a := range attrs {
+		if value, ok := lastValue(v.Attrs, a.Key); !ok || value != a.Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) StartSpan(name string, attrs ...metrics.Attr) metrics.Span {
+	return r.start(context.Background(), name, attrs)
+}
+
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...metrics.Attr) (context.Context, metrics.Span) {
+	s := r.start(ctx, name, attrs)
+
+	return context.WithValue(metrics.ConsumeIntendedStart(ctx), spanContextKey{}, s), s
+}
+
+func (r *Recorder) start(ctx context.Context, name string, attrs []metrics.Attr) *Span {
+	s := &Span{
+		Name:     name,
+		Attrs:    slices.Clone(attrs),
+		Start:    time.Now(),
+		recorder: r,
+	}
+
+	if parent, ok := ctx.Value(spanContextKey{}).(*Span); ok && parent.recorder == r {
+		s.Parent = parent
+	}
+
+	if t, ok := metrics.IntendedStart(ctx); ok {
+		s.Intended = t
+	}
+
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+
+	return s
+}
+
+func (r *Recorder) Add(name string, delta int64, attrs ...metrics.Attr) {
+	r.mu.Lock()
+	r.counters = append(r.counters, Value{Name: name, Attrs: slices.Clone(attrs), Value: float64(delta)})
+	r.mu.Unlock()
+}
+
+func (r *Recorder) SetGauge(name string, value float64, attrs ...metrics.Attr) {
+	r.mu.Lock()
+	r.gauges = append(r.gauges, Value{Name: name, Attrs: slices.Clone(attrs), Value: value})
+	r.mu.Unlock()
+}
+
+// Counter sums increases of the counter having all given attributes
+func (r *Recorder) Counter(name string, attrs ...metrics.Attr) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sum int64
+	for _, c := range r.counters {
+		if c.matches(name, attrs) {
+			sum += int64(c.Value)
+		}
+	}
+
+	return sum
+}
+
+// Gauge returns the last value set for the gauge having all given attributes
+func (r *Recorder) Gauge(name string, attrs ...metrics.Attr) (float64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := len(r.gauges) - 1; i >= 0; i-- {
+		if r.gauges[i].matches(name, attrs) {
+			return r.gauges[i].Value, true
+		}
+	}
+
+	return 0, false
+}
+
+// Spans returns all started spans in start order
+func (r *Recorder) Spans() []*Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.spans)
+}
+
+// Find returns spans with the name in start order
+func (r *Recorder) Find(name string) []*Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var found []*Span
+	for _, s := range r.spans {
+		if s.Name == name {
+			found = append(found, s)
+		}
+	}
+
+	return found
+}
+
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.spans = nil
+	r.doubleDone = nil
+	r.counters = nil
+	r.gauges = nil
+	r.mu.Unlock()
+}
+
+// Span returns the only finished span with the name, the test fails if there is none or several
+func (r *Recorder) Span(t testing.TB, name string) *Span {
+	t.Helper()
+
+	var finished []*Span
+	for _, s := range r.Find(name) {
+		if s.Finished {
+			finished = append(finished, s)
+		}
+	}
+
+	switch len(finished) {
+	case 0:
+		t.Fatalf("span %s is not finished", name)
+	case 1:
+	default:
+		t.Fatalf("span %s finished %d times, expected once", name, len(finished))
+	}
+
+	return finished[0]
+}
+
+// AssertOK checks span finished without error
+func (r *Recorder) AssertOK(t testing.TB, name string) bool {
+	t.Helper()
+
+	s := r.Span(t, name)
+	if s.Err != nil {
+		t.Errorf("span %s finished with error %v, expected no error", name, s.Err)
+		return false
+	}
+
+	return true
+}
+
+// AssertError checks span finished with an error matching target with errors.Is
+func (r *Recorder) AssertError(t testing.TB, name string, target error) bool {
+	t.Helper()
+
+	s := r.Span(t, name)
+	if !errors.Is(s.Err, target) {
+		t.Errorf("span %s finished with error %v, expected %v", name, s.Err, target)
+		return false
+	}
+
+	return true
+}
+
+// AssertAttr checks the last value of the attribute key
+func (r *Recorder) AssertAttr(t testing.TB, name string, attr metrics.Attr) bool {
+	t.Helper()
+
+	value, ok := r.Span(t, name).Attr(attr.Key)
+	if !ok || value != attr.Value {
+		t.Errorf("span %s has %s=%q, expected %q", name, attr.Key, value, attr.Value)
+		return false
+	}
+
+	return true
+}
+
+// AssertArg checks the last value of the argument key
+func (r *Recorder) AssertArg(t testing.TB, name string, arg metrics.Attr) bool {
+	t.Helper()
+
+	value, ok := r.Span(t, name).Arg(arg.Key)
+	if !ok || value != arg.Value {
+		t.Errorf("span %s has argument %s=%q, expected %q", name, arg.Key, value, arg.Value)
+		return false
+	}
+
+	return true
+}
+
+// AssertCounter checks the sum of counter increases having all given attributes
+func (r *Recorder) AssertCounter(t testing.TB, name string, want int64, attrs ...metrics.Attr) bool {
+	t.Helper()
+
+	if got := r.Counter(name, attrs...); got != want {
+		t.Errorf("counter %s%v is %d, expected %d", name, attrs, got, want)
+		return false
+	}
+
+	return true
+}
+
+// AssertGauge checks the last gauge value having all given attributes
+func (r *Recorder) AssertGauge(t testing.TB, name string, want float64, attrs ...metrics.Attr) bool {
+	t.Helper()
+
+	got, ok := r.Gauge(name, attrs...)
+	if !ok || got != want {
+		t.Errorf("gauge %s%v is %v, expected %v", name, attrs, got, want)
+		return false
+	}
+
+	return true
+}
+
+// AssertCovers checks children are direct children of parent and finished within its start and end
+func (r *Recorder) AssertCovers(t testing.TB, parent string, children ...string) bool {
+	t.Helper()
+
+	p := r.Span(t, parent)
+
+	ok := true
+	for _, name := range children {
+		c := r.Span(t, name)
+
+		if c.Parent != p {
+			t.Errorf("span %s is not a child of %s", name, parent)
+			ok = false
+			continue
+		}
+
+		if c.Start.Before(p.Start) || c.End.After(p.End) {
+			t.Errorf("span %s [%s, %s] is not covered by %s [%s, %s]",
+				name, c.Start.Format(time.StampMicro), c.End.Format(time.StampMicro),
+				parent, p.Start.Format(time.StampMicro), p.End.Format(time.StampMicro))
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// AssertAllDone checks every started span finished exactly once
+func (r *Recorder) AssertAllDone(t testing.TB) bool {
+	t.Helper()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ok := true
+	for _, s := range r.spans {
+		if !s.Finished {
+			t.Errorf("span %s is not finished", s.Name)
+			ok = false
+		}
+	}
+
+	for _, name := range r.doubleDone {
+		t.Errorf("span %s finished more than once", name)
+		ok = false
+	}
+
+	return ok
+}
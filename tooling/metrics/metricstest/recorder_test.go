@@ -0,0 +1,93 @@
+package metricstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rusinikita/system-design-trainer/tooling/metrics"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	boom := errors.New("boom")
+
+	intended := time.Now().Add(-time.Second)
+	ctx, root := r.Start(metrics.WithIntendedStart(context.Background(), intended), "UserDashboard", metrics.String("page", "1"))
+	_, child := r.Start(ctx, "GetArticleFeed")
+	child.SetAttributes(metrics.String("cache", "miss"))
+	child.SetArgs(metrics.Int("user_id", 7))
+	child.Done(boom)
+	root.Done(nil)
+
+	r.AssertOK(t, "UserDashboard")
+	r.AssertError(t, "GetArticleFeed", boom)
+	r.AssertAttr(t, "UserDashboard", metrics.String("page", "1"))
+	r.AssertAttr(t, "GetArticleFeed", metrics.String("cache", "miss"))
+	r.AssertArg(t, "GetArticleFeed", metrics.Int("user_id", 7))
+	r.AssertCovers(t, "UserDashboard", "GetArticleFeed")
+	r.AssertAllDone(t)
+
+	feed := r.Span(t, "GetArticleFeed")
+	assert.True(t, feed.Intended.IsZero(), "children don't inherit intended start")
+	assert.Equal(t, intended, r.Span(t, "UserDashboard").Intended)
+	assert.GreaterOrEqual(t, feed.Duration(), time.Duration(0))
+}
+
+func TestRecorderCountersAndGauges(t *testing.T) {
+	r := NewRecorder()
+
+	r.Add("cache.requests", 1, metrics.String("result", "hit"))
+	r.Add("cache.requests", 2, metrics.String("result", "hit"))
+	r.Add("cache.requests", 1, metrics.String("result", "miss"))
+	r.SetGauge("cache.size_bytes", 10)
+	r.SetGauge("cache.size_bytes", 20)
+
+	r.AssertCounter(t, "cache.requests", 4)
+	r.AssertCounter(t, "cache.requests", 3, metrics.String("result", "hit"))
+	r.AssertCounter(t, "cache.invalidations", 0)
+	r.AssertGauge(t, "cache.size_bytes", 20)
+
+	_, ok := r.Gauge("queue.depth")
+	assert.False(t, ok)
+}
+
+func TestRecorderFailures(t *testing.T) {
+	r := NewRecorder()
+
+	_, parent := r.Start(context.Background(), "parent")
+	parent.Done(nil)
+	parent.Done(nil)
+
+	_, unrelated := r.Start(context.Background(), "unrelated")
+	unrelated.Done(errors.New("boom"))
+
+	r.StartSpan("pending")
+
+	mock := &fakeTB{}
+	assert.False(t, r.AssertError(mock, "unrelated", context.Canceled))
+	assert.False(t, r.AssertOK(mock, "unrelated"))
+	assert.False(t, r.AssertCovers(mock, "parent", "unrelated"))
+	assert.False(t, r.AssertAllDone(mock))
+
+	assert.Equal(t, 5, mock.errors)
+
+	r.Reset()
+	require.Empty(t, r.Spans())
+}
+
+// fakeTB counts failures of assertions expected to fail
+type fakeTB struct {
+	testing.TB
+	errors int
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(string, ...any) {
+	f.errors++
+}
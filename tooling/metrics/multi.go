@@ -0,0 +1,67 @@
+package metrics
+
+import "context"
+
+// Multi fans every span out to all given implementations, e.g. CSV logs and Prometheus.
+func Multi(obs ...Obs) Obs {
+	return multiObs(obs)
+}
+
+type multiObs []Obs
+
+func (m multiObs) StartSpan(name string, attrs ...Attr) (span Span) {
+	spans := make(multiSpan, len(m))
+	for i, o := range m {
+		spans[i] = o.StartSpan(name, attrs...)
+	}
+
+	return spans
+}
+
+func (m multiObs) Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span) {
+	intended, hasIntended := IntendedStart(ctx)
+
+	spans := make(multiSpan, len(m))
+	for i, o := range m {
+		// every implementation should see intended start, not only the first one
+		if hasIntended {
+			ctx = WithIntendedStart(ctx, intended)
+		}
+
+		ctx, spans[i] = o.Start(ctx, name, attrs...)
+	}
+
+	return ConsumeIntendedStart(ctx), spans
+}
+
+func (m multiObs) Add(name string, delta int64, attrs ...Attr) {
+	for _, o := range m {
+		o.Add(name, delta, attrs...)
+	}
+}
+
+func (m multiObs) SetGauge(name string, value float64, attrs ...Attr) {
+	for _, o := range m {
+		o.SetGauge(name, value, attrs...)
+	}
+}
+
+type multiSpan []Span
+
+func (m multiSpan) SetAttributes(attrs ...Attr) {
+	for _, s := range m {
+		s.SetAttributes(attrs...)
+	}
+}
+
+func (m multiSpan) SetArgs(args ...Attr) {
+	for _, s := range m {
+		s.SetArgs(args...)
+	}
+}
+
+func (m multiSpan) Done(err error) {
+	for _, s := range m {
+		s.Done(err)
+	}
+}
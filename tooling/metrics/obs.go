@@ -2,79 +2,442 @@ package metrics
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type metricChan chan result
-type logsChan chan logRecords
-type logRecords []byte
+type logsChan chan *Window
 
-type observability struct {
-	file        io.Writer
+// Observability aggregates spans into wall-clock aligned windows, one second by default, and writes them to sinks.
+// Use StartLogging to run the pipeline and Close to flush everything at the end of a run.
+type Observability struct {
+	sinks       []Sink
+	exporters   []TraceExporter
+	attrSets    int
+	exemplars   int
+	window      time.Duration
+	sliding     int
+	overflow    OverflowPolicy
+	sampleEvery int64
+	samplers    []Sampler
 	metricsChan metricChan
 	logsChan    logsChan
+
+	instruments      *instruments
+	overflowCounters overflowCounters
+
+	// sending is held by record around the send, shutdown takes it to know no send is in progress
+	sending sync.RWMutex
+	stopped atomic.Bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
+}
+
+type Option func(o *Observability)
+
+// WithAttrSetsLimit caps distinct attribute sets per span name, the rest is aggregated as overflow=true
+func WithAttrSetsLimit(limit int) Option {
+	return func(o *Observability) {
+		o.attrSets = limit
+	}
+}
+
+// WithExemplars sets how many slowest spans per key and attribute set each window keeps, 0 disables them
+func WithExemplars(n int) Option {
+	return func(o *Observability) {
+		o.exemplars = max(n, 0)
+	}
+}
+
+// WithWindow sets the aggregation window, it's clamped to 100ms-1m.
+// Windows end on multiples of the size in wall-clock time, so logs of different processes line up.
+func WithWindow(size time.Duration) Option {
+	return func(o *Observability) {
+		o.window = min(max(size, minWindow), maxWindow)
+	}
+}
+
+// WithSlidingWindow makes every written window cover the last n windows, so percentiles are smoothed.
+// Windows are still written once per window size and overlap, don't sum their counts up
+// (SummarySink accounts for that).
+func WithSlidingWindow(n int) Option {
+	return func(o *Observability) {
+		o.sliding = max(n, 1)
+	}
+}
+
+// WithSinks adds outputs for aggregated windows
+func WithSinks(sinks ...Sink) Option {
+	return func(o *Observability) {
+		o.sinks = append(o.sinks, sinks...)
+	}
+}
+
+// WithSamplers adds gauges polled every window, e.g. NewRuntimeSampler and NewDBStatsSampler
+func WithSamplers(samplers ...Sampler) Option {
+	return func(o *Observability) {
+		o.samplers = append(o.samplers, samplers...)
+	}
+}
+
+// WithTraceWriter enables per-request trace records, one JSON object per line
+func WithTraceWriter(w io.Writer) Option {
+	if w == nil {
+		return WithTraceExporters()
+	}
+
+	return WithTraceExporters(&jsonTraceExporter{w: w})
+}
+
+// WithTraceExporters adds outputs for per-request traces, e.g. NewOTLPFileExporter and NewOTLPHTTPExporter
+func WithTraceExporters(exporters ...TraceExporter) Option {
+	return func(o *Observability) {
+		o.exporters = append(o.exporters, exporters...)
+	}
+}
+
+// WithOverflow sets what happens to spans when the aggregator can't keep up, OverflowBlock by default
+func WithOverflow(policy OverflowPolicy) Option {
+	return func(o *Observability) {
+		o.overflow = policy
+	}
+}
+
+// WithSampleEvery sets how many spans one kept span stands for under OverflowSample
+func WithSampleEvery(n int) Option {
+	return func(o *Observability) {
+		o.sampleEvery = int64(max(n, 1))
+	}
 }
 
-func NewDefault() (Obs, error) {
+// WithBufferSize sets how many finished spans can wait for the aggregator
+func WithBufferSize(size int) Option {
+	return func(o *Observability) {
+		o.metricsChan = make(metricChan, size)
+	}
+}
+
+func NewDefault(opts ...Option) (*Observability, error) {
 	f, err := os.Create("log.csv")
 	if err != nil {
 		return nil, fmt.Errorf("error opening file: %v", err)
 	}
 
-	return New(f), nil
+	return New(f, opts...), nil
 }
 
-func New(writer io.Writer) Obs {
-	c := make(metricChan, 10000)
+// New creates a pipeline writing CSV to writer, nil writer is allowed when WithSinks is used.
+// Writers implementing Sync or io.Closer are synced and closed by Close.
+func New(writer io.Writer, opts ...Option) *Observability {
+	c := make(metricChan, defaultBufferSize)
 	l := make(logsChan, 10)
 
-	return &observability{
-		file:        writer,
+	var sinks []Sink
+	if writer != nil {
+		sinks = append(sinks, NewCSVSink(writer))
+	}
+
+	o := &Observability{
+		sinks:       sinks,
 		metricsChan: c,
 		logsChan:    l,
+		attrSets:    defaultAttrSetsLimit,
+		exemplars:   defaultExemplars,
+		window:      defaultWindow,
+		sliding:     1,
+		sampleEvery: defaultSampleEvery,
+	}
+
+	for _, opt := range opts {
+		opt(o)
 	}
+
+	o.instruments = newInstruments(o.attrSets)
+
+	return o
+}
+
+func (o *Observability) StartSpan(name string, attrs ...Attr) (span Span) {
+	return newSpan(context.Background(), name, attrs, o)
 }
 
-func (o *observability) StartSpan(name string) (span Span) {
-	return newSpan(name, o.metricsChan)
+func (o *Observability) Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span) {
+	s := newSpan(ctx, name, attrs, o)
+
+	return context.WithValue(ConsumeIntendedStart(ctx), spanContextKey{obs: o}, s), s
+}
+
+func (o *Observability) Add(name string, delta int64, attrs ...Attr) {
+	o.instruments.add(name, delta, attrs)
+}
+
+func (o *Observability) SetGauge(name string, value float64, attrs ...Attr) {
+	o.instruments.set(name, value, attrs)
+}
+
+// record passes a finished span to the aggregator, spans finished after shutdown are discarded
+func (o *Observability) record(r result) {
+	o.sending.RLock()
+	defer o.sending.RUnlock()
+
+	if o.stopped.Load() {
+		return
+	}
+
+	switch o.overflow {
+	case OverflowBlock:
+		o.metricsChan <- r
+		return
+
+	case OverflowSample:
+		if r.err == nil && len(o.metricsChan) >= cap(o.metricsChan)/2 {
+			counter := o.overflowCounters.get(r.key)
+			if counter.seq.Add(1)%o.sampleEvery != 0 {
+				counter.sampled.Add(1)
+				return
+			}
+
+			r.weight = o.sampleEvery
+		}
+	}
+
+	select {
+	case o.metricsChan <- r:
+	default:
+		o.overflowCounters.get(r.key).dropped.Add(max(r.weight, 1))
+	}
 }
 
-func (o *observability) MakeLogs(cancel <-chan struct{}) {
-	ticker := time.NewTicker(time.Second)
+// MakeLogs aggregates spans into windows until cancel is closed,
+// then drains buffered spans, flushes the last partial window and closes the logs channel.
+func (o *Observability) MakeLogs(cancel <-chan struct{}) {
+	defer close(o.logsChan)
+
+	dims := newDimensions(o.attrSets)
 	var buffer []result
 
+	// samplers may take a few database round trips, they run aside so spans keep being drained
+	var samples atomic.Pointer[[]GaugeValue]
+	stopSampling := make(chan struct{})
+	samplingDone := make(chan struct{})
+	go o.runSamplers(&samples, stopSampling, samplingDone)
+
+	windowStart := time.Now()
+	windowEnd := nextWindowEnd(windowStart, o.window)
+	timer := time.NewTimer(time.Until(windowEnd))
+	defer timer.Stop()
+
+	var steps []*Window
+
+	flush := func(end time.Time) {
+		window := newWindow(buffer, dims, o.overflowCounters.snapshot(), o.exemplars, windowStart, end)
+		counters, gauges := o.instruments.snapshot()
+		window.Counters = counters
+		if sampled := samples.Load(); sampled != nil {
+			gauges = append(gauges, *sampled...)
+		}
+		window.Gauges = gauges
+		windowStart = end
+
+		if len(o.exporters) > 0 {
+			window.Traces = collectTraces(buffer)
+		}
+		buffer = buffer[:0]
+
+		if o.sliding > 1 {
+			steps = append(steps, window)
+			if len(steps) > o.sliding {
+				steps = steps[1:]
+			}
+
+			window = slide(steps)
+		}
+
+		if len(window.Spans) == 0 && len(window.Counters) == 0 && len(window.Gauges) == 0 {
+			return
+		}
+
+		o.logsChan <- window
+	}
+
 	for {
 		select {
 		case r := <-o.metricsChan:
 			buffer = append(buffer, r)
-		case <-ticker.C:
-			logs := newLogs(buffer)
-			clear(buffer)
 
-			o.logsChan <- logs
+		case <-timer.C:
+			flush(windowEnd)
+
+			// a slow flush can miss boundaries, the next window is longer then
+			now := time.Now()
+			if now.Before(windowEnd) {
+				now = windowEnd
+			}
+			windowEnd = nextWindowEnd(now, o.window)
+			timer.Reset(time.Until(windowEnd))
 
 		case <-cancel:
+			// senders blocked on the full channel hold the lock, keep draining until it's taken
+			stopped := make(chan struct{})
+			go func() {
+				o.sending.Lock()
+				o.stopped.Store(true)
+				o.sending.Unlock()
+				close(stopped)
+			}()
+
+		waitSenders:
+			for {
+				select {
+				case r := <-o.metricsChan:
+					buffer = append(buffer, r)
+				case <-stopped:
+					break waitSenders
+				}
+			}
+
+			// nothing waits for the channel anymore, the last window gets fresh samples
+			close(stopSampling)
+			<-samplingDone
+			if len(o.samplers) > 0 {
+				values := sample(o.samplers)
+				samples.Store(&values)
+			}
+
+			for {
+				select {
+				case r := <-o.metricsChan:
+					buffer = append(buffer, r)
+				default:
+					flush(time.Now())
+					return
+				}
+			}
+		}
+	}
+}
+
+// runSamplers polls samplers at start and then at every window boundary, flush attaches the latest values
+func (o *Observability) runSamplers(samples *atomic.Pointer[[]GaugeValue], stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	if len(o.samplers) == 0 {
+		return
+	}
+
+	for {
+		values := sample(o.samplers)
+		samples.Store(&values)
+
+		timer := time.NewTimer(time.Until(nextWindowEnd(time.Now(), o.window)))
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
 			return
+		}
+	}
+}
 
-		default:
+// nextWindowEnd is the first wall-clock multiple of size after t
+func nextWindowEnd(t time.Time, size time.Duration) time.Time {
+	return t.Truncate(size).Add(size)
+}
+
+func collectTraces(results []result) []*Trace {
+	var traces []*Trace
+	for _, r := range results {
+		if r.trace != nil {
+			traces = append(traces, r.trace)
 		}
 	}
+
+	return traces
 }
 
-func (o *observability) WriteLogs() {
-	for ll := range o.logsChan {
-		_, err := o.file.Write(ll)
-		if err != nil {
-			panic(fmt.Errorf("failed to write logs to file: %v", err))
+// WriteLogs passes windows to sinks until the logs channel is closed.
+// A failed sink gets no more windows, the channel is drained anyway, so aggregation never stalls.
+// Returns the first error of every failed output.
+func (o *Observability) WriteLogs() error {
+	sinkErrs := make([]error, len(o.sinks))
+	exporterErrs := make([]error, len(o.exporters))
+
+	for window := range o.logsChan {
+		for i, sink := range o.sinks {
+			if sinkErrs[i] != nil {
+				continue
+			}
+
+			sinkErrs[i] = sink.Write(window)
+		}
+
+		if len(window.Traces) == 0 {
+			continue
 		}
+
+		for i, exporter := range o.exporters {
+			if exporterErrs[i] != nil {
+				continue
+			}
+
+			exporterErrs[i] = exporter.Export(window.Traces)
+		}
+	}
+
+	return errors.Join(append(sinkErrs, exporterErrs...)...)
+}
+
+// AddSamplers adds samplers to a created pipeline, e.g. of a connection pool that needs obs to be opened.
+// Samplers are read by the running pipeline, so it fails once StartLogging is called.
+func (o *Observability) AddSamplers(samplers ...Sampler) error {
+	if o.done != nil {
+		return errors.New("samplers can't be added after logging started")
 	}
+
+	o.samplers = append(o.samplers, samplers...)
+
+	return nil
 }
 
-func (o *observability) StartLogging(ctx context.Context) {
+// StartLogging runs the pipeline in background. Canceling ctx stops it the same way as Close,
+// but only Close waits for the final flush and reports errors.
+func (o *Observability) StartLogging(ctx context.Context) {
+	ctx, o.cancel = context.WithCancel(ctx)
+	o.done = make(chan struct{})
+
 	go o.MakeLogs(ctx.Done())
 
-	go o.WriteLogs()
+	go func() {
+		defer close(o.done)
+
+		errs := []error{o.WriteLogs()}
+		for _, sink := range o.sinks {
+			errs = append(errs, sink.Close())
+		}
+		for _, exporter := range o.exporters {
+			errs = append(errs, exporter.Close())
+		}
+
+		o.err = errors.Join(errs...)
+	}()
+}
+
+// Close stops accepting spans, flushes the last partial window, syncs and closes writers
+// and returns the first write error of the run.
+func (o *Observability) Close() error {
+	if o.done == nil {
+		o.StartLogging(context.Background())
+	}
+
+	o.cancel()
+	<-o.done
+
+	return o.err
 }
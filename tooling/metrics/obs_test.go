@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+type closingBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closingBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestCloseFlushesPartialWindow(t *testing.T) {
+	logs := &closingBuffer{}
+	traces := &bytes.Buffer{}
+
+	o := New(logs, WithTraceWriter(traces))
+	o.StartLogging(context.Background())
+
+	ctx, root := o.Start(context.Background(), "UserDashboard")
+	_, child := o.Start(ctx, "GetArticleFeed")
+	child.Done(nil)
+	root.Done(nil)
+
+	require.NoError(t, o.Close())
+
+	assert.True(t, logs.closed)
+	assert.Len(t, readCSVRows(t, logs.String()), 2)
+	assert.Equal(t, 1, strings.Count(traces.String(), "\n"))
+
+	// spans after shutdown must not block or panic
+	o.StartSpan("late").Done(nil)
+}
+
+func TestCloseWithoutStart(t *testing.T) {
+	logs := &bytes.Buffer{}
+
+	o := New(logs)
+	o.StartSpan("op").Done(nil)
+
+	require.NoError(t, o.Close())
+	rows := readCSVRows(t, logs.String())
+	require.Len(t, rows, 1)
+	assert.Equal(t, "op", rows[0]["key"])
+}
+
+func TestCloseReportsWriteError(t *testing.T) {
+	o := New(failingWriter{})
+	o.StartLogging(context.Background())
+
+	o.StartSpan("op").Done(nil)
+
+	assert.ErrorContains(t, o.Close(), "disk full")
+}
+
+func TestCloseDoesNotStrandBlockedSpans(t *testing.T) {
+	o := New(&bytes.Buffer{}, WithBufferSize(1))
+	o.StartLogging(context.Background())
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				o.StartSpan("op").Done(nil)
+			}
+		}()
+	}
+
+	require.NoError(t, o.Close())
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("spans finished around shutdown stay blocked")
+	}
+}
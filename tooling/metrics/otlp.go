@@ -0,0 +1,282 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	otlpScope          = "github.com/rusinikita/system-design-trainer/tooling/metrics"
+	otlpDefaultService = "system-design-trainer"
+	otlpHTTPTimeout    = 5 * time.Second
+	// otlpHTTPQueueSize is how many exports, one per window, wait for a slow collector before traces are dropped
+	otlpHTTPQueueSize = 64
+
+	otlpSpanKindInternal = 1
+	otlpSpanKindServer   = 2
+
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+// OTLP/JSON encoding of ExportTraceServiceRequest: ids are hex, 64-bit integers are strings
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScopeInfo `json:"scope"`
+	Spans []otlpSpan    `json:"spans"`
+}
+
+type otlpScopeInfo struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           TraceID        `json:"traceId"`
+	SpanID            SpanID         `json:"spanId"`
+	ParentSpanID      SpanID         `json:"parentSpanId"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func newOTLPRequest(service string, traces []*Trace) otlpRequest {
+	var spans []otlpSpan
+	for _, t := range traces {
+		for _, s := range t.Spans {
+			spans = append(spans, newOTLPSpan(t.TraceID, s))
+		}
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{otlpString("service.name", service)},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScopeInfo{Name: otlpScope},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+func newOTLPSpan(traceID TraceID, s SpanRecord) otlpSpan {
+	span := otlpSpan{
+		TraceID:           traceID,
+		SpanID:            s.SpanID,
+		ParentSpanID:      s.ParentID,
+		Name:              s.Name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.End().UnixNano(), 10),
+		Status:            otlpStatus{Code: otlpStatusOK},
+	}
+
+	// root spans are incoming requests
+	if s.ParentID.IsZero() {
+		span.Kind = otlpSpanKindServer
+	}
+
+	for _, a := range sortAttrs(slices.Concat(s.Attrs, s.Args)) {
+		span.Attributes = append(span.Attributes, otlpString(a.Key, a.Value))
+	}
+
+	if s.Err != "" {
+		span.Status = otlpStatus{Code: otlpStatusError, Message: s.Err}
+	}
+
+	return span
+}
+
+func otlpString(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: value}}
+}
+
+// OTLPFileExporter writes traces in OTLP JSON file format: one ExportTraceServiceRequest per line.
+// Files can be replayed into a collector or opened in Jaeger and Tempo compatible viewers.
+type OTLPFileExporter struct {
+	w       io.Writer
+	service string
+}
+
+// NewOTLPFileExporter reports traces as service.name=service, empty service means system-design-trainer
+func NewOTLPFileExporter(w io.Writer, service string) *OTLPFileExporter {
+	if service == "" {
+		service = otlpDefaultService
+	}
+
+	return &OTLPFileExporter{w: w, service: service}
+}
+
+func (e *OTLPFileExporter) Export(traces []*Trace) error {
+	if err := json.NewEncoder(e.w).Encode(newOTLPRequest(e.service, traces)); err != nil {
+		return fmt.Errorf("failed to write otlp traces: %v", err)
+	}
+
+	return nil
+}
+
+func (e *OTLPFileExporter) Close() error {
+	return closeWriter(e.w)
+}
+
+// OTLPHTTPExporter posts traces as OTLP/HTTP JSON to a collector, e.g. http://localhost:4318.
+// Posts run in background, so a slow or down collector doesn't hold the pipeline and the tested code.
+type OTLPHTTPExporter struct {
+	url     string
+	service string
+	client  *http.Client
+
+	queue   chan []*Trace
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+	dropped atomic.Int64
+	// err is the first send failure, it's read after done is closed
+	err error
+}
+
+// NewOTLPHTTPExporter sends to endpoint + /v1/traces unless endpoint already has the path
+func NewOTLPHTTPExporter(endpoint, service string) *OTLPHTTPExporter {
+	if service == "" {
+		service = otlpDefaultService
+	}
+
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	e := &OTLPHTTPExporter{
+		url:     url,
+		service: service,
+		client:  &http.Client{Timeout: otlpHTTPTimeout},
+		queue:   make(chan []*Trace, otlpHTTPQueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go e.run()
+
+	return e
+}
+
+// Export queues traces for sending, when the queue is full they are dropped and counted in Dropped
+func (e *OTLPHTTPExporter) Export(traces []*Trace) error {
+	select {
+	case e.queue <- traces:
+	default:
+		e.dropped.Add(int64(len(traces)))
+	}
+
+	return nil
+}
+
+// Dropped is the number of traces not sent because the collector didn't keep up
+func (e *OTLPHTTPExporter) Dropped() int64 {
+	return e.dropped.Load()
+}
+
+func (e *OTLPHTTPExporter) run() {
+	defer close(e.done)
+
+	for traces := range e.queue {
+		if e.ctx.Err() != nil {
+			e.dropped.Add(int64(len(traces)))
+			continue
+		}
+
+		if err := e.send(traces); err != nil && e.err == nil {
+			e.err = err
+		}
+	}
+}
+
+func (e *OTLPHTTPExporter) send(traces []*Trace) error {
+	body, err := json.Marshal(newOTLPRequest(e.service, traces))
+	if err != nil {
+		return fmt.Errorf("failed to encode otlp traces: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(e.ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send otlp traces: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send otlp traces: %v", err)
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to send otlp traces: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Close sends queued traces, it gives up after otlpHTTPTimeout and counts the rest as dropped.
+// It returns the first send failure.
+func (e *OTLPHTTPExporter) Close() error {
+	close(e.queue)
+
+	timer := time.NewTimer(otlpHTTPTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-e.done:
+	case <-timer.C:
+		e.cancel()
+		<-e.done
+	}
+
+	e.cancel()
+	e.client.CloseIdleConnections()
+
+	return e.err
+}
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTLPFileExporter(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	o := New(nil, WithTraceExporters(NewOTLPFileExporter(out, "")))
+	o.StartLogging(context.Background())
+
+	ctx, root := o.Start(context.Background(), "UserDashboard", String("page", "1"))
+	_, feed := o.Start(ctx, "GetArticleFeed")
+	feed.Done(errors.New("boom"))
+	root.Done(nil)
+
+	require.NoError(t, o.Close())
+
+	var req otlpRequest
+	require.NoError(t, json.Unmarshal(out.Bytes(), &req))
+	require.Len(t, req.ResourceSpans, 1)
+	assert.Equal(t, otlpString("service.name", otlpDefaultService), req.ResourceSpans[0].Resource.Attributes[0])
+
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+
+	rootSpan, feedSpan := spans[0], spans[1]
+	assert.Equal(t, "UserDashboard", rootSpan.Name)
+	assert.Equal(t, otlpSpanKindServer, rootSpan.Kind)
+	assert.True(t, rootSpan.ParentSpanID.IsZero())
+	assert.Equal(t, []otlpKeyValue{otlpString("page", "1")}, rootSpan.Attributes)
+	assert.Equal(t, otlpStatusOK, rootSpan.Status.Code)
+
+	assert.Equal(t, rootSpan.TraceID, feedSpan.TraceID)
+	assert.Equal(t, rootSpan.SpanID, feedSpan.ParentSpanID)
+	assert.Equal(t, otlpSpanKindInternal, feedSpan.Kind)
+	assert.Equal(t, otlpStatus{Code: otlpStatusError, Message: "boom"}, feedSpan.Status)
+
+	// ids are hex as OTLP/JSON requires
+	assert.Contains(t, out.String(), `"traceId":"`+rootSpan.TraceID.String()+`"`)
+	assert.Contains(t, out.String(), `"parentSpanId":""`)
+}
+
+func TestOTLPHTTPExporter(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	trace := testTrace(10, 5, 9)
+
+	e := NewOTLPHTTPExporter(server.URL, "trainer")
+	require.NoError(t, e.Export([]*Trace{&trace}))
+	require.NoError(t, e.Close())
+
+	var req otlpRequest
+	require.NoError(t, json.Unmarshal(body, &req))
+	assert.Len(t, req.ResourceSpans[0].ScopeSpans[0].Spans, 3)
+	assert.Equal(t, "trainer", req.ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+}
+
+func TestOTLPHTTPExporterStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	trace := testTrace(10, 5, 9)
+
+	e := NewOTLPHTTPExporter(server.URL+"/v1/traces", "")
+	require.NoError(t, e.Export([]*Trace{&trace}))
+	assert.ErrorContains(t, e.Close(), "503")
+}
+
+func TestOTLPHTTPExporterSlowCollector(t *testing.T) {
+	release := make(chan struct{})
+	var received atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+
+		var req otlpRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		received.Add(int64(len(req.ResourceSpans[0].ScopeSpans[0].Spans)))
+	}))
+	defer server.Close()
+
+	trace := testTrace(10, 5, 9)
+	e := NewOTLPHTTPExporter(server.URL, "")
+
+	// exports don't wait for the collector, the ones over the queue are dropped
+	start := time.Now()
+	for range otlpHTTPQueueSize + 10 {
+		require.NoError(t, e.Export([]*Trace{&trace}))
+	}
+	assert.Less(t, time.Since(start), time.Second)
+	assert.GreaterOrEqual(t, e.Dropped(), int64(9))
+
+	close(release)
+	require.NoError(t, e.Close())
+
+	sent := otlpHTTPQueueSize + 10 - e.Dropped()
+	assert.Equal(t, sent*3, received.Load())
+}
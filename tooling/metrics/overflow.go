@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what span.Done does when the aggregator falls behind
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for free buffer space, measurements stay exact but may slow down the system under test
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards spans that don't fit into the buffer
+	OverflowDropNewest
+	// OverflowSample keeps every n-th successful span once the buffer is half full and weights it by n,
+	// failed spans are always kept, spans that still don't fit are dropped
+	OverflowSample
+)
+
+const (
+	defaultBufferSize  = 10000
+	defaultSampleEvery = 10
+)
+
+// overflowCounts are spans lost by the overflow policy since the last window
+type overflowCounts struct {
+	dropped int64
+	sampled int64
+}
+
+type overflowCounter struct {
+	dropped atomic.Int64
+	sampled atomic.Int64
+	// seq picks every n-th span of the key, a shared sequence would skew keep rates of interleaved keys
+	seq atomic.Int64
+}
+
+type overflowCounters struct {
+	m sync.Map // span key -> *overflowCounter
+}
+
+func (c *overflowCounters) get(key string) *overflowCounter {
+	v, ok := c.m.Load(key)
+	if !ok {
+		v, _ = c.m.LoadOrStore(key, &overflowCounter{})
+	}
+
+	return v.(*overflowCounter)
+}
+
+// snapshot returns non-zero counters and resets them
+func (c *overflowCounters) snapshot() map[string]overflowCounts {
+	counts := map[string]overflowCounts{}
+
+	c.m.Range(func(k, v any) bool {
+		counter := v.(*overflowCounter)
+
+		oc := overflowCounts{
+			dropped: counter.dropped.Swap(0),
+			sampled: counter.sampled.Swap(0),
+		}
+		if oc != (overflowCounts{}) {
+			counts[k.(string)] = oc
+		}
+
+		return true
+	})
+
+	return counts
+}
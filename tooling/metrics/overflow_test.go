@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func overflowColumns(t *testing.T, logs string) (count, dropped, sampled string) {
+	rows := readCSVRows(t, logs)
+	require.Len(t, rows, 1)
+
+	return rows[0]["count"], rows[0]["dropped"], rows[0]["sampled"]
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	logs := &bytes.Buffer{}
+	o := New(logs, WithBufferSize(2), WithOverflow(OverflowDropNewest))
+
+	for range 5 {
+		o.StartSpan("op").Done(nil)
+	}
+
+	require.NoError(t, o.Close())
+
+	count, dropped, sampled := overflowColumns(t, logs.String())
+	assert.Equal(t, "2", count)
+	assert.Equal(t, "3", dropped)
+	assert.Equal(t, "0", sampled)
+}
+
+func TestOverflowSample(t *testing.T) {
+	logs := &bytes.Buffer{}
+	o := New(logs, WithBufferSize(4), WithOverflow(OverflowSample), WithSampleEvery(2))
+
+	// 2 exact spans fill half of the buffer, then every second span is kept with weight 2
+	for range 5 {
+		o.StartSpan("op").Done(nil)
+	}
+	// errors are never sampled out
+	o.StartSpan("op").Done(errors.New("boom"))
+
+	require.NoError(t, o.Close())
+
+	count, dropped, sampled := overflowColumns(t, logs.String())
+	assert.Equal(t, "5", count)
+	assert.Equal(t, "0", dropped)
+	assert.Equal(t, "2", sampled)
+}
+
+func TestOverflowSamplePerKey(t *testing.T) {
+	o := New(nil, WithBufferSize(100), WithOverflow(OverflowSample), WithSampleEvery(2))
+
+	for range 50 {
+		o.StartSpan("fill").Done(nil)
+	}
+	// interleaved keys keep every second span each, not all spans of one key
+	for range 20 {
+		o.StartSpan("a").Done(nil)
+		o.StartSpan("b").Done(nil)
+	}
+
+	kept := map[string]int{}
+	for len(o.metricsChan) > 0 {
+		kept[(<-o.metricsChan).key]++
+	}
+
+	assert.Equal(t, 10, kept["a"])
+	assert.Equal(t, 10, kept["b"])
+}
@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const outcomeOK = "ok"
+
+// PrometheusObs records spans into a histogram labelled by span name and outcome,
+// counters and gauges into vectors labelled by name, and serves them for scraping.
+type PrometheusObs struct {
+	latency  *prometheus.HistogramVec
+	counters *prometheus.CounterVec
+	gauges   *prometheus.GaugeVec
+	server   *http.Server
+	listener net.Listener
+	// served gets the server failure, Close reports it
+	served chan error
+}
+
+// NewPrometheus starts a /metrics listener on addr, ":0" picks a free port.
+func NewPrometheus(addr string) (*PrometheusObs, error) {
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:                   "trainer",
+		Name:                        "span_duration_seconds",
+		Help:                        "Duration of instrumented operations.",
+		Buckets:                     prometheus.ExponentialBuckets(0.0005, 2, 16),
+		NativeHistogramBucketFactor: 1.1,
+	}, []string{"span", "outcome"})
+
+	counters := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "trainer",
+		Name:      "counter_total",
+		Help:      "Counters reported through Obs.Add.",
+	}, []string{"name"})
+
+	gauges := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "trainer",
+		Name:      "gauge",
+		Help:      "Gauges reported through Obs.SetGauge.",
+	}, []string{"name"})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(latency, counters, gauges)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error listening metrics addr: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	p := &PrometheusObs{
+		latency:  latency,
+		counters: counters,
+		gauges:   gauges,
+		server:   &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second},
+		listener: listener,
+		served:   make(chan error, 1),
+	}
+
+	go func() {
+		p.served <- serve(p.server, listener)
+	}()
+
+	return p, nil
+}
+
+func (p *PrometheusObs) Addr() string {
+	return p.listener.Addr().String()
+}
+
+// StartSpan ignores attributes: label sets are fixed to span and outcome to keep series count predictable
+func (p *PrometheusObs) StartSpan(name string, _ ...Attr) (span Span) {
+	return &promSpan{
+		key:     name,
+		start:   time.Now(),
+		latency: p.latency,
+	}
+}
+
+func (p *PrometheusObs) Start(ctx context.Context, name string, _ ...Attr) (context.Context, Span) {
+	return ctx, p.StartSpan(name)
+}
+
+// Add ignores attributes like StartSpan, negative deltas are dropped as Prometheus counters only grow
+func (p *PrometheusObs) Add(name string, delta int64, _ ...Attr) {
+	if delta < 0 {
+		return
+	}
+
+	p.counters.WithLabelValues(name).Add(float64(delta))
+}
+
+func (p *PrometheusObs) SetGauge(name string, value float64, _ ...Attr) {
+	p.gauges.WithLabelValues(name).Set(value)
+}
+
+// Close stops the server, it also returns the error the server failed with during the run
+func (p *PrometheusObs) Close(ctx context.Context) error {
+	if err := p.server.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	if err := <-p.served; err != nil {
+		return fmt.Errorf("metrics server failed: %v", err)
+	}
+
+	return nil
+}
+
+// serve runs the server until Shutdown, a stopped server isn't a failure
+func serve(server *http.Server, listener net.Listener) error {
+	err := server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+type promSpan struct {
+	key     string
+	start   time.Time
+	latency *prometheus.HistogramVec
+}
+
+func (s *promSpan) SetAttributes(...Attr) {}
+
+func (s *promSpan) SetArgs(...Attr) {}
+
+func (s *promSpan) Done(err error) {
+	outcome := outcomeOK
+	if err != nil {
+		outcome = classifyError(err).String()
+	}
+
+	s.latency.WithLabelValues(s.key, outcome).Observe(time.Since(s.start).Seconds())
+}
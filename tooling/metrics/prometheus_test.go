@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusObs(t *testing.T) {
+	p, err := NewPrometheus("127.0.0.1:0")
+	require.NoError(t, err)
+	defer p.Close(context.Background())
+
+	csv := &bytes.Buffer{}
+	obs := Multi(New(csv), p)
+
+	obs.StartSpan("GetArticleFeed").Done(nil)
+	obs.StartSpan("GetArticleFeed").Done(context.DeadlineExceeded)
+	obs.Add("cache.hit", 3, String("cache", "feed"))
+	obs.SetGauge("cache.size_bytes", 1024)
+
+	resp, err := http.Get("http://" + p.Addr() + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), `trainer_span_duration_seconds_count{outcome="ok",span="GetArticleFeed"} 1`)
+	assert.Contains(t, string(body), `trainer_span_duration_seconds_count{outcome="deadline",span="GetArticleFeed"} 1`)
+	assert.Contains(t, string(body), `trainer_counter_total{name="cache.hit"} 3`)
+	assert.Contains(t, string(body), `trainer_gauge{name="cache.size_bytes"} 1024`)
+}
@@ -1,7 +1,7 @@
 package metrics
 
 import (
-	"fmt"
+	"cmp"
 	"slices"
 	"time"
 )
@@ -9,52 +9,82 @@ import (
 type result struct {
 	key      string
 	start    time.Time
+	intended time.Time
 	duration time.Duration
 	err      error
+	attrs    []Attr
+	args     []Attr
+	traceID  TraceID
+	spanID   SpanID
+	trace    *Trace
+	// weight is how many spans the result stands for when sampled
+	weight int64
 }
 
-type logData struct {
-	key       string
-	count     int64
-	ts        time.Time
-	durations []time.Duration
-}
-
-func (d logData) appendRecord(bytes []byte) []byte {
-	slices.Sort(d.durations)
+// response is the time from intended start to the end, it equals duration when the scheduler gave no intended start
+func (r result) response() time.Duration {
+	if r.intended.IsZero() || r.intended.After(r.start) {
+		return r.duration
+	}
 
-	p99 := d.durations[int(float32(len(d.durations))*0.99)-1]
-	p98 := d.durations[int(float32(len(d.durations))*0.98)-1]
-	p95 := d.durations[int(float32(len(d.durations))*0.95)-1]
+	return r.start.Sub(r.intended) + r.duration
+}
 
-	return fmt.Appendf(bytes, "%s,%d,%d,%d,%d,%d\n", d.key, d.ts.UnixMilli(), d.count, p99, p98, p95)
+type dimensionKey struct {
+	key   string
+	attrs string
 }
 
-func newLogs(results []result) logRecords {
-	m := map[string]*logData{}
+// aggregate groups results by key and attribute set, keeping up to exemplars slowest spans of each.
+// Overflow counts are known only per key, they go to the key row without attributes.
+func aggregate(results []result, dims *dimensions, overflow map[string]overflowCounts, exemplars int, now time.Time) map[dimensionKey]*SpanStats {
+	m := map[dimensionKey]*SpanStats{}
 
 	for _, r := range results {
-		d, ok := m[r.key]
+		attrs, attrsKey := dims.attrs(r.key, r.attrs)
+		k := dimensionKey{key: r.key, attrs: attrsKey}
+
+		s, ok := m[k]
 		if !ok {
-			d = &logData{
-				key: r.key,
-				ts:  r.start,
-			}
-			m[r.key] = d
+			s = newSpanStats(k.key, attrs, r.start)
+			m[k] = s
 		}
 
-		if r.start.After(d.ts) {
-			d.ts = r.start
+		s.add(r)
+		s.addExemplar(r, exemplars)
+	}
+
+	for key, counts := range overflow {
+		k := dimensionKey{key: key}
+
+		s, ok := m[k]
+		if !ok {
+			s = newSpanStats(key, nil, now)
+			m[k] = s
 		}
 
-		d.count++
-		d.durations = append(d.durations, r.duration)
+		s.Dropped = counts.dropped
+		s.Sampled = counts.sampled
+	}
+
+	return m
+}
+
+func newWindow(results []result, dims *dimensions, overflow map[string]overflowCounts, exemplars int, start, now time.Time) *Window {
+	m := aggregate(results, dims, overflow, exemplars, now)
+
+	keys := make([]dimensionKey, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
+	slices.SortFunc(keys, func(a, b dimensionKey) int {
+		return cmp.Or(cmp.Compare(a.key, b.key), cmp.Compare(a.attrs, b.attrs))
+	})
 
-	bytes := make([]byte, 0, 1000)
-	for _, d := range m {
-		bytes = d.appendRecord(bytes)
+	w := &Window{Start: start, Time: now}
+	for _, k := range keys {
+		w.Spans = append(w.Spans, m[k])
 	}
 
-	return bytes
+	return w
 }
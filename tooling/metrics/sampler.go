@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+const samplerTimeout = 500 * time.Millisecond
+
+// GaugeValue is a point-in-time value added to the window timeline
+type GaugeValue struct {
+	Name  string  `json:"name"`
+	Attrs []Attr  `json:"attrs,omitempty"`
+	Value float64 `json:"value"`
+}
+
+// Sampler is polled once per window aside from the aggregator, it gets samplerTimeout to answer.
+// Values that are counters on the source side should be reported as per-window deltas.
+// Errors don't fail the run, they are counted by sampler.errors gauge with sampler=<type name>.
+type Sampler interface {
+	Sample(ctx context.Context) ([]GaugeValue, error)
+}
+
+// RuntimeSampler reports Go runtime stats: goroutines, heap, allocation rate and GC pauses
+type RuntimeSampler struct {
+	lastTime       time.Time
+	lastTotalAlloc uint64
+	lastNumGC      uint32
+}
+
+func NewRuntimeSampler() *RuntimeSampler {
+	s := &RuntimeSampler{}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	s.remember(&ms, time.Now())
+
+	return s
+}
+
+func (s *RuntimeSampler) remember(ms *runtime.MemStats, now time.Time) {
+	s.lastTime = now
+	s.lastTotalAlloc = ms.TotalAlloc
+	s.lastNumGC = ms.NumGC
+}
+
+func (s *RuntimeSampler) Sample(context.Context) ([]GaugeValue, error) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	now := time.Now()
+
+	pauses := s.pauses(&ms)
+	pause := func(q float64) float64 {
+		if len(pauses) == 0 {
+			return 0
+		}
+
+		return pauses[min(int(q*float64(len(pauses))), len(pauses)-1)].Seconds()
+	}
+
+	values := []GaugeValue{
+		{Name: "runtime.goroutines", Value: float64(runtime.NumGoroutine())},
+		{Name: "runtime.gomaxprocs", Value: float64(runtime.GOMAXPROCS(0))},
+		{Name: "runtime.heap_inuse_bytes", Value: float64(ms.HeapInuse)},
+		{Name: "runtime.alloc_bytes_per_second", Value: float64(ms.TotalAlloc-s.lastTotalAlloc) / now.Sub(s.lastTime).Seconds()},
+		{Name: "runtime.gc_count", Value: float64(ms.NumGC - s.lastNumGC)},
+		{Name: "runtime.gc_pause_p50_seconds", Value: pause(0.5)},
+		{Name: "runtime.gc_pause_p99_seconds", Value: pause(0.99)},
+		{Name: "runtime.gc_pause_max_seconds", Value: pause(1)},
+	}
+
+	s.remember(&ms, now)
+
+	return values, nil
+}
+
+// pauses returns sorted GC pauses since the last sample, runtime keeps only the last 256 of them
+func (s *RuntimeSampler) pauses(ms *runtime.MemStats) []time.Duration {
+	n := min(int(ms.NumGC-s.lastNumGC), len(ms.PauseNs))
+
+	pauses := make([]time.Duration, 0, n)
+	for i := range n {
+		idx := (int(ms.NumGC) - 1 - i + len(ms.PauseNs)) % len(ms.PauseNs)
+		pauses = append(pauses, time.Duration(ms.PauseNs[idx]))
+	}
+	slices.Sort(pauses)
+
+	return pauses
+}
+
+// DBStatsSampler reports sql.DB connection pool usage, wait count and duration are per-window deltas
+type DBStatsSampler struct {
+	attrs []Attr
+	db    *sql.DB
+	last  sql.DBStats
+}
+
+func NewDBStatsSampler(name string, db *sql.DB) *DBStatsSampler {
+	return &DBStatsSampler{
+		attrs: []Attr{String("db", name)},
+		db:    db,
+		last:  db.Stats(),
+	}
+}
+
+func (s *DBStatsSampler) Sample(context.Context) ([]GaugeValue, error) {
+	stats := s.db.Stats()
+
+	gauge := func(name string, v float64) GaugeValue {
+		return GaugeValue{Name: name, Attrs: s.attrs, Value: v}
+	}
+
+	values := []GaugeValue{
+		gauge("db.max_open_connections", float64(stats.MaxOpenConnections)),
+		gauge("db.open_connections", float64(stats.OpenConnections)),
+		gauge("db.in_use", float64(stats.InUse)),
+		gauge("db.idle", float64(stats.Idle)),
+		gauge("db.wait_count", float64(stats.WaitCount-s.last.WaitCount)),
+		gauge("db.wait_duration_seconds", (stats.WaitDuration - s.last.WaitDuration).Seconds()),
+	}
+
+	s.last = stats
+
+	return values, nil
+}
+
+// sample polls all samplers. A failure, e.g. a timeout, is reported by sampler.errors gauge
+// instead of failing the run: a missed sample doesn't make windows wrong.
+func sample(samplers []Sampler) []GaugeValue {
+	ctx, cancel := context.WithTimeout(context.Background(), samplerTimeout)
+	defer cancel()
+
+	var values []GaugeValue
+	for _, s := range samplers {
+		v, err := s.Sample(ctx)
+
+		failed := 0.0
+		if err != nil {
+			failed = 1
+		}
+
+		values = append(values, v...)
+		values = append(values, GaugeValue{
+			Name:  "sampler.errors",
+			Attrs: []Attr{String("sampler", samplerName(s))},
+			Value: failed,
+		})
+	}
+
+	return values
+}
+
+// samplerName is the sampler type name without package, e.g. RuntimeSampler
+func samplerName(s Sampler) string {
+	name := fmt.Sprintf("%T", s)
+
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gaugeValues(values []GaugeValue) map[string]float64 {
+	m := map[string]float64{}
+	for _, v := range values {
+		m[v.Name] = v.Value
+	}
+
+	return m
+}
+
+func TestRuntimeSampler(t *testing.T) {
+	s := NewRuntimeSampler()
+
+	runtime.GC()
+
+	values, err := s.Sample(context.Background())
+	require.NoError(t, err)
+
+	m := gaugeValues(values)
+	assert.GreaterOrEqual(t, m["runtime.goroutines"], 1.0)
+	assert.Equal(t, float64(runtime.GOMAXPROCS(0)), m["runtime.gomaxprocs"])
+	assert.Positive(t, m["runtime.heap_inuse_bytes"])
+	assert.GreaterOrEqual(t, m["runtime.gc_count"], 1.0)
+	assert.LessOrEqual(t, m["runtime.gc_pause_p50_seconds"], m["runtime.gc_pause_max_seconds"])
+}
+
+func TestDBStatsSampler(t *testing.T) {
+	// sql.Open doesn't connect, pool stats are available right away
+	db, err := sql.Open("postgres", "host=localhost")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(25)
+
+	values, err := NewDBStatsSampler("postgres", db).Sample(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 25.0, gaugeValues(values)["db.max_open_connections"])
+	assert.Equal(t, []Attr{String("db", "postgres")}, values[0].Attrs)
+}
+
+type failingSampler struct{}
+
+func (failingSampler) Sample(context.Context) ([]GaugeValue, error) {
+	return []GaugeValue{{Name: "queue.depth", Value: 3}}, errors.New("sampler failed")
+}
+
+func TestSamplersInTimeline(t *testing.T) {
+	out := &bytes.Buffer{}
+	summary := NewSummarySink(nil, nil)
+
+	o := New(out, WithSamplers(failingSampler{}), WithSinks(summary))
+	o.StartSpan("op").Done(nil)
+
+	// a failed sample is counted, it doesn't fail the run
+	require.NoError(t, o.Close())
+
+	rows := readCSVRows(t, out.String())
+	require.Len(t, rows, 3)
+	assert.Equal(t, csvKindSpan, rows[0]["kind"])
+	assert.Equal(t, csvKindGauge, rows[1]["kind"])
+	assert.Equal(t, "queue.depth", rows[1]["key"])
+	assert.Equal(t, "3", rows[1]["value"])
+	assert.Equal(t, "sampler.errors", rows[2]["key"])
+	assert.Equal(t, "sampler=failingSampler", rows[2]["attrs"])
+	assert.Equal(t, "1", rows[2]["value"])
+
+	require.Len(t, summary.Summary().Gauges, 2)
+	assert.Equal(t, 3.0, summary.Summary().Gauges[0].Max)
+}
+
+type slowSampler struct{}
+
+func (slowSampler) Sample(context.Context) ([]GaugeValue, error) {
+	time.Sleep(300 * time.Millisecond)
+	return []GaugeValue{{Name: "slow", Value: 1}}, nil
+}
+
+func TestSlowSamplerDoesNotBlockSpans(t *testing.T) {
+	o := New(nil, WithBufferSize(1), WithWindow(100*time.Millisecond), WithSamplers(slowSampler{}))
+	o.StartLogging(context.Background())
+
+	var slowest time.Duration
+	for end := time.Now().Add(400 * time.Millisecond); time.Now().Before(end); {
+		start := time.Now()
+		o.StartSpan("op").Done(nil)
+		slowest = max(slowest, time.Since(start))
+	}
+
+	require.NoError(t, o.Close())
+	assert.Less(t, slowest, 100*time.Millisecond)
+}
+
+func TestAddSamplers(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	o := New(out)
+	require.NoError(t, o.AddSamplers(failingSampler{}))
+
+	o.StartLogging(context.Background())
+	assert.Error(t, o.AddSamplers(NewRuntimeSampler()))
+	require.NoError(t, o.Close())
+
+	rows := readCSVRows(t, out.String())
+	require.NotEmpty(t, rows)
+	assert.Equal(t, "queue.depth", rows[0]["key"])
+}
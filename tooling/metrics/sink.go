@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+)
+
+// SchemaVersion is bumped on every change of sink output columns or fields
+const SchemaVersion = 5
+
+// Sink receives aggregated windows from the writer goroutine
+type Sink interface {
+	Write(w *Window) error
+	// Close is called once after the last window
+	Close() error
+}
+
+func closeWriter(w io.Writer) error {
+	var err error
+
+	if s, ok := w.(interface{ Sync() error }); ok {
+		err = s.Sync()
+	}
+
+	if c, ok := w.(io.Closer); ok {
+		err = errors.Join(err, c.Close())
+	}
+
+	return err
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	csvKindSpan    = "span"
+	csvKindCounter = "counter"
+	csvKindGauge   = "gauge"
+)
+
+var csvHeader = []string{
+	"schema_version", "window_start", "window_end", "ts", "kind", "key", "attrs", "value",
+	"count", "success", "errors", "error_rate",
+	"err_deadline", "err_canceled", "err_sql", "err_other",
+	"dropped", "sampled",
+	"p50_ns", "p90_ns", "p95_ns", "p99_ns", "p999_ns", "max_ns", "mean_ns",
+	"resp_p50_ns", "resp_p90_ns", "resp_p95_ns", "resp_p99_ns", "resp_p999_ns", "resp_max_ns", "resp_mean_ns",
+}
+
+// CSVSink writes one row per span key and attribute set and one row per counter and gauge, the first row is a header.
+// The kind column tells rows apart, columns of another kind are left empty.
+type CSVSink struct {
+	file          io.Writer
+	csv           *csv.Writer
+	headerWritten bool
+}
+
+func NewCSVSink(w io.Writer) *CSVSink {
+	return &CSVSink{
+		file: w,
+		csv:  csv.NewWriter(w),
+	}
+}
+
+func (s *CSVSink) Write(w *Window) error {
+	if !s.headerWritten {
+		if err := s.csv.Write(csvHeader); err != nil {
+			return fmt.Errorf("failed to write csv header: %v", err)
+		}
+		s.headerWritten = true
+	}
+
+	for _, stats := range w.Spans {
+		if err := s.csv.Write(csvRecord(w, stats)); err != nil {
+			return fmt.Errorf("failed to write csv record: %v", err)
+		}
+	}
+
+	for _, c := range w.Counters {
+		if err := s.csv.Write(csvValueRecord(w, csvKindCounter, c.Name, c.Attrs, float64(c.Value))); err != nil {
+			return fmt.Errorf("failed to write csv record: %v", err)
+		}
+	}
+
+	for _, g := range w.Gauges {
+		if err := s.csv.Write(csvValueRecord(w, csvKindGauge, g.Name, g.Attrs, g.Value)); err != nil {
+			return fmt.Errorf("failed to write csv record: %v", err)
+		}
+	}
+
+	s.csv.Flush()
+
+	return s.csv.Error()
+}
+
+func (s *CSVSink) Close() error {
+	return closeWriter(s.file)
+}
+
+// csvRecord has window boundaries and ts, the latest span start, in unix milliseconds
+func csvRecord(w *Window, s *SpanStats) []string {
+	l := s.Latency.Summary()
+	rl := s.Response.Summary()
+
+	i := func(v int64) string {
+		return strconv.FormatInt(v, 10)
+	}
+	d := func(v time.Duration) string {
+		return i(int64(v))
+	}
+
+	return []string{
+		i(SchemaVersion), i(w.Start.UnixMilli()), i(w.Time.UnixMilli()), i(s.Timestamp.UnixMilli()),
+		csvKindSpan, s.Key, s.AttrsString(), "",
+		i(s.Count), i(s.Success), i(s.Errors), strconv.FormatFloat(s.ErrorRate(), 'f', 4, 64),
+		i(s.Deadline), i(s.Canceled), i(s.SQL), i(s.Other),
+		i(s.Dropped), i(s.Sampled),
+		d(l.P50), d(l.P90), d(l.P95), d(l.P99), d(l.P999), d(l.Max), d(l.Mean),
+		d(rl.P50), d(rl.P90), d(rl.P95), d(rl.P99), d(rl.P999), d(rl.Max), d(rl.Mean),
+	}
+}
+
+// csvValueRecord is a counter or gauge row, values are taken at the window end, so ts equals window_end
+func csvValueRecord(w *Window, kind, name string, attrs []Attr, value float64) []string {
+	end := strconv.FormatInt(w.Time.UnixMilli(), 10)
+
+	record := make([]string, len(csvHeader))
+	copy(record, []string{
+		strconv.Itoa(SchemaVersion), strconv.FormatInt(w.Start.UnixMilli(), 10), end, end,
+		kind, name, formatAttrs(attrs), strconv.FormatFloat(value, 'g', -1, 64),
+	})
+
+	return record
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+type windowJSON struct {
+	SchemaVersion int             `json:"schema_version"`
+	Start         time.Time       `json:"start"`
+	Time          time.Time       `json:"time"`
+	Spans         []spanStatsJSON `json:"spans"`
+	Counters      []CounterValue  `json:"counters,omitempty"`
+	Gauges        []GaugeValue    `json:"gauges,omitempty"`
+}
+
+type spanStatsJSON struct {
+	Key       string         `json:"key"`
+	Attrs     []Attr         `json:"attrs,omitempty"`
+	Timestamp time.Time      `json:"ts"`
+	Count     int64          `json:"count"`
+	Success   int64          `json:"success"`
+	Errors    int64          `json:"errors"`
+	ErrorRate float64        `json:"error_rate"`
+	Deadline  int64          `json:"err_deadline"`
+	Canceled  int64          `json:"err_canceled"`
+	SQL       int64          `json:"err_sql"`
+	Other     int64          `json:"err_other"`
+	Dropped   int64          `json:"dropped"`
+	Sampled   int64          `json:"sampled"`
+	Latency   LatencySummary `json:"latency"`
+	Response  LatencySummary `json:"response"`
+}
+
+func newWindowJSON(w *Window) windowJSON {
+	j := windowJSON{
+		SchemaVersion: SchemaVersion,
+		Start:         w.Start,
+		Time:          w.Time,
+		Spans:         make([]spanStatsJSON, 0, len(w.Spans)),
+		Counters:      w.Counters,
+		Gauges:        w.Gauges,
+	}
+
+	for _, s := range w.Spans {
+		j.Spans = append(j.Spans, spanStatsJSON{
+			Key:       s.Key,
+			Attrs:     s.Attrs,
+			Timestamp: s.Timestamp,
+			Count:     s.Count,
+			Success:   s.Success,
+			Errors:    s.Errors,
+			ErrorRate: s.ErrorRate(),
+			Deadline:  s.Deadline,
+			Canceled:  s.Canceled,
+			SQL:       s.SQL,
+			Other:     s.Other,
+			Dropped:   s.Dropped,
+			Sampled:   s.Sampled,
+			Latency:   s.Latency.Summary(),
+			Response:  s.Response.Summary(),
+		})
+	}
+
+	return j
+}
+
+// JSONLSink writes one JSON object per window
+type JSONLSink struct {
+	file    io.Writer
+	encoder *json.Encoder
+}
+
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{
+		file:    w,
+		encoder: json.NewEncoder(w),
+	}
+}
+
+func (s *JSONLSink) Write(w *Window) error {
+	if err := s.encoder.Encode(newWindowJSON(w)); err != nil {
+		return fmt.Errorf("failed to write json window: %v", err)
+	}
+
+	return nil
+}
+
+func (s *JSONLSink) Close() error {
+	return closeWriter(s.file)
+}
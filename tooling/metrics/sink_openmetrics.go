@@ -0,0 +1,276 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// omChunkPoints is how many points of a series are kept in memory before spilling them to a temp file
+const omChunkPoints = 256
+
+// OpenMetricsSink writes windows as OpenMetrics text with a timestamp on every sample,
+// the format promtool and other backfill tools import.
+// Points of a series must go together, so nothing is written to w until Close. Memory stays bounded:
+// points are formatted and spilled to a temp file in chunks, Close assembles the file from them.
+// A crashed run leaves no OpenMetrics output, use CSV or JSON Lines sinks alongside when that matters.
+type OpenMetricsSink struct {
+	file        io.Writer
+	families    []*omFamily
+	byName      map[string]*omFamily
+	chunkPoints int
+	spill       *os.File
+	spillSize   int64
+	err         error
+}
+
+type omFamily struct {
+	name   string
+	help   string
+	series map[string]*omSeries
+	order  []*omSeries
+}
+
+// omSeries has formatted lines not spilled yet and spilled chunks in write order
+type omSeries struct {
+	buf    []byte
+	points int
+	chunks []omChunk
+}
+
+type omChunk struct {
+	offset int64
+	size   int64
+}
+
+func NewOpenMetricsSink(w io.Writer) *OpenMetricsSink {
+	s := &OpenMetricsSink{
+		file:        w,
+		byName:      map[string]*omFamily{},
+		chunkPoints: omChunkPoints,
+	}
+
+	s.family("trainer_span_count", "Spans finished in the window.")
+	s.family("trainer_span_errors", "Failed spans in the window by error class.")
+	s.family("trainer_span_dropped", "Spans dropped by overflow policy in the window.")
+	s.family("trainer_span_sampled", "Spans skipped by overflow sampling in the window.")
+	s.family("trainer_span_latency_seconds", "Span latency percentiles in the window, quantile 1 is max.")
+	s.family("trainer_span_latency_mean_seconds", "Mean span latency in the window.")
+	s.family("trainer_span_response_seconds", "Response time from intended start percentiles in the window, quantile 1 is max.")
+
+	return s
+}
+
+func (s *OpenMetricsSink) family(name, help string) {
+	f := &omFamily{
+		name:   name,
+		help:   help,
+		series: map[string]*omSeries{},
+	}
+
+	s.families = append(s.families, f)
+	s.byName[name] = f
+}
+
+// add formats a point, the first spill error is kept and returned by Write
+func (s *OpenMetricsSink) add(name, labels string, value float64, ts time.Time) {
+	f := s.byName[name]
+
+	series, ok := f.series[labels]
+	if !ok {
+		series = &omSeries{}
+		f.series[labels] = series
+		f.order = append(f.order, series)
+	}
+
+	series.buf = append(series.buf, name...)
+	if labels != "" {
+		series.buf = append(series.buf, '{')
+		series.buf = append(series.buf, labels...)
+		series.buf = append(series.buf, '}')
+	}
+	series.buf = append(series.buf, ' ')
+	series.buf = strconv.AppendFloat(series.buf, value, 'g', -1, 64)
+	series.buf = append(series.buf, ' ')
+	series.buf = strconv.AppendFloat(series.buf, float64(ts.UnixMilli())/1000, 'f', 3, 64)
+	series.buf = append(series.buf, '\n')
+	series.points++
+
+	if series.points >= s.chunkPoints && s.err == nil {
+		s.err = s.spillSeries(series)
+	}
+}
+
+func (s *OpenMetricsSink) spillSeries(series *omSeries) error {
+	if s.spill == nil {
+		f, err := os.CreateTemp("", "openmetrics-*")
+		if err != nil {
+			return fmt.Errorf("failed to create openmetrics spill file: %v", err)
+		}
+		s.spill = f
+	}
+
+	n, err := s.spill.Write(series.buf)
+	if err != nil {
+		return fmt.Errorf("failed to spill openmetrics points: %v", err)
+	}
+
+	series.chunks = append(series.chunks, omChunk{offset: s.spillSize, size: int64(n)})
+	s.spillSize += int64(n)
+	series.buf = series.buf[:0]
+	series.points = 0
+
+	return nil
+}
+
+func (s *OpenMetricsSink) Write(w *Window) error {
+	for _, stats := range w.Spans {
+		labels := omLabels(stats)
+		with := func(name, value string) string {
+			return labels + "," + name + `="` + value + `"`
+		}
+
+		s.add("trainer_span_count", labels, float64(stats.Count), w.Time)
+		s.add("trainer_span_errors", with("class", errDeadline.String()), float64(stats.Deadline), w.Time)
+		s.add("trainer_span_errors", with("class", errCanceled.String()), float64(stats.Canceled), w.Time)
+		s.add("trainer_span_errors", with("class", errSQL.String()), float64(stats.SQL), w.Time)
+		s.add("trainer_span_errors", with("class", errOther.String()), float64(stats.Other), w.Time)
+		s.add("trainer_span_dropped", labels, float64(stats.Dropped), w.Time)
+		s.add("trainer_span_sampled", labels, float64(stats.Sampled), w.Time)
+
+		l := stats.Latency.Summary()
+		for q, v := range l.quantiles() {
+			s.add("trainer_span_latency_seconds", with("quantile", q), v.Seconds(), w.Time)
+		}
+		s.add("trainer_span_latency_mean_seconds", labels, l.Mean.Seconds(), w.Time)
+
+		for q, v := range stats.Response.Summary().quantiles() {
+			s.add("trainer_span_response_seconds", with("quantile", q), v.Seconds(), w.Time)
+		}
+	}
+
+	for _, c := range w.Counters {
+		name := omMetricName(c.Name) + "_increase"
+		if _, ok := s.byName[name]; !ok {
+			s.family(name, "Increase of "+c.Name+" counter in the window.")
+		}
+
+		s.add(name, omAttrLabels(c.Attrs), float64(c.Value), w.Time)
+	}
+
+	for _, g := range w.Gauges {
+		name := omMetricName(g.Name)
+		if _, ok := s.byName[name]; !ok {
+			s.family(name, "Last "+g.Name+" gauge value in the window.")
+		}
+
+		s.add(name, omAttrLabels(g.Attrs), g.Value, w.Time)
+	}
+
+	return s.err
+}
+
+// omMetricName turns a dotted name into a trainer_ prefixed metric name
+func omMetricName(name string) string {
+	return "trainer_" + omLabelName(strings.ReplaceAll(name, ".", "_"))
+}
+
+func (s *OpenMetricsSink) Close() error {
+	err := s.writeAll()
+	if s.spill != nil {
+		err = errors.Join(err, s.spill.Close(), os.Remove(s.spill.Name()))
+	}
+
+	return errors.Join(err, closeWriter(s.file))
+}
+
+func (s *OpenMetricsSink) writeAll() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	b := bufio.NewWriter(s.file)
+
+	for _, f := range s.families {
+		if len(f.order) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(b, "# TYPE %s gauge\n# HELP %s %s\n", f.name, f.name, f.help)
+
+		for _, series := range f.order {
+			for _, c := range series.chunks {
+				if _, err := io.Copy(b, io.NewSectionReader(s.spill, c.offset, c.size)); err != nil {
+					return fmt.Errorf("failed to read openmetrics spill file: %v", err)
+				}
+			}
+			b.Write(series.buf)
+		}
+	}
+
+	b.WriteString("# EOF\n")
+
+	if err := b.Flush(); err != nil {
+		return fmt.Errorf("failed to write openmetrics: %v", err)
+	}
+
+	return nil
+}
+
+var omReservedLabels = map[string]bool{"span": true, "class": true, "quantile": true}
+
+func omLabels(s *SpanStats) string {
+	labels := `span="` + omEscape(s.Key) + `"`
+	if len(s.Attrs) > 0 {
+		labels += "," + omAttrLabels(s.Attrs)
+	}
+
+	return labels
+}
+
+func omAttrLabels(attrs []Attr) string {
+	var b strings.Builder
+
+	for i, a := range attrs {
+		name := omLabelName(a.Key)
+		if omReservedLabels[name] {
+			name = "attr_" + name
+		}
+
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name + `="`)
+		b.WriteString(omEscape(a.Value))
+		b.WriteByte('"')
+	}
+
+	return b.String()
+}
+
+func omLabelName(key string) string {
+	name := []byte(key)
+	for i, c := range name {
+		valid := c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9'
+		if !valid {
+			name[i] = '_'
+		}
+	}
+
+	if len(name) == 0 {
+		return "_"
+	}
+
+	return string(name)
+}
+
+var omEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func omEscape(v string) string {
+	return omEscaper.Replace(v)
+}
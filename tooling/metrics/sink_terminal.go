@@ -0,0 +1,220 @@
+package metrics
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	terminalHistory = 40
+	// move the cursor home and clear the screen
+	terminalClear = "\x1b[H\x1b[2J"
+)
+
+var sparkRunes = []rune("▁▂▃▄▅▆▇█")
+
+// TerminalSink redraws a live view of the run on every window:
+// per key RPS, error rate, p50 and p99 with sparklines of the last windows, counter rates and gauges.
+type TerminalSink struct {
+	w             io.Writer
+	gaugePrefixes []string
+	start         time.Time
+
+	spans    map[dimensionKey]*terminalSeries
+	counters map[dimensionKey]*terminalSeries
+	gauges   map[dimensionKey]*terminalSeries
+}
+
+type terminalSeries struct {
+	key       dimensionKey
+	rps       float64
+	errorRate float64
+	// idle is set when the key had no spans in the last window, e.g. it stalls
+	idle   bool
+	p50    []float64
+	p99    []float64
+	values []float64
+}
+
+// NewTerminalSink draws to w, usually os.Stdout. Gauges are shown when their name has one of the prefixes,
+// e.g. "db." for the connection pool, no prefixes show every gauge.
+func NewTerminalSink(w io.Writer, gaugePrefixes ...string) *TerminalSink {
+	return &TerminalSink{
+		w:             w,
+		gaugePrefixes: gaugePrefixes,
+		spans:         map[dimensionKey]*terminalSeries{},
+		counters:      map[dimensionKey]*terminalSeries{},
+		gauges:        map[dimensionKey]*terminalSeries{},
+	}
+}
+
+func (s *TerminalSink) Write(w *Window) error {
+	if s.start.IsZero() {
+		s.start = w.Start
+	}
+
+	seen := map[*terminalSeries]bool{}
+
+	for _, stats := range w.Spans {
+		series := s.series(s.spans, dimensionKey{key: stats.Key, attrs: stats.AttrsString()})
+		series.rps = rate(stats.Count, w.Duration())
+		series.errorRate = stats.ErrorRate()
+		series.idle = stats.Count == 0
+		series.p50 = appendHistory(series.p50, float64(stats.Latency.Quantile(0.5)))
+		series.p99 = appendHistory(series.p99, float64(stats.Latency.Quantile(0.99)))
+		seen[series] = true
+	}
+
+	for _, c := range w.Counters {
+		series := s.series(s.counters, dimensionKey{key: c.Name, attrs: formatAttrs(c.Attrs)})
+		series.values = appendHistory(series.values, rate(c.Value, w.Duration()))
+		seen[series] = true
+	}
+
+	// keys missing from the window completed nothing, showing their last values would hide a stall
+	for _, series := range s.spans {
+		if !seen[series] {
+			series.rps, series.errorRate, series.idle = 0, 0, true
+			series.p50 = appendHistory(series.p50, 0)
+			series.p99 = appendHistory(series.p99, 0)
+		}
+	}
+	for _, series := range s.counters {
+		if !seen[series] {
+			series.values = appendHistory(series.values, 0)
+		}
+	}
+
+	for _, g := range w.Gauges {
+		if !s.showGauge(g.Name) {
+			continue
+		}
+
+		series := s.series(s.gauges, dimensionKey{key: g.Name, attrs: formatAttrs(g.Attrs)})
+		series.values = appendHistory(series.values, g.Value)
+	}
+
+	if _, err := s.w.Write(s.render(w.Time)); err != nil {
+		return fmt.Errorf("failed to draw terminal view: %v", err)
+	}
+
+	return nil
+}
+
+// Close leaves the last frame on the screen, the writer is not closed
+func (s *TerminalSink) Close() error {
+	return nil
+}
+
+func (s *TerminalSink) series(m map[dimensionKey]*terminalSeries, k dimensionKey) *terminalSeries {
+	series, ok := m[k]
+	if !ok {
+		series = &terminalSeries{key: k}
+		m[k] = series
+	}
+
+	return series
+}
+
+func (s *TerminalSink) showGauge(name string) bool {
+	if len(s.gaugePrefixes) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(s.gaugePrefixes, func(prefix string) bool {
+		return strings.HasPrefix(name, prefix)
+	})
+}
+
+func (s *TerminalSink) render(now time.Time) []byte {
+	b := &bytes.Buffer{}
+	b.WriteString(terminalClear)
+
+	fmt.Fprintf(b, "Load test +%s\n\n", now.Sub(s.start).Round(time.Second))
+
+	tw := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "SPAN\tATTRS\tRPS\tERROR RATE\tP50\tP99\tP50 TREND\tP99 TREND\t")
+	for _, series := range sortedSeries(s.spans) {
+		p50, p99 := "-", "-"
+		if !series.idle {
+			p50 = formatLatency(time.Duration(series.p50[len(series.p50)-1]))
+			p99 = formatLatency(time.Duration(series.p99[len(series.p99)-1]))
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%.1f\t%.2f%%\t%s\t%s\t%s\t%s\t\n",
+			series.key.key, series.key.attrs, series.rps, series.errorRate*100,
+			p50, p99, sparkline(series.p50), sparkline(series.p99),
+		)
+	}
+
+	if len(s.counters) > 0 {
+		fmt.Fprintln(tw, "\t\t\t\t\t\t\t\t")
+		fmt.Fprintln(tw, "COUNTER\tATTRS\tPER SECOND\tTREND\t")
+		for _, series := range sortedSeries(s.counters) {
+			fmt.Fprintf(tw, "%s\t%s\t%.1f\t%s\t\n",
+				series.key.key, series.key.attrs, series.values[len(series.values)-1], sparkline(series.values))
+		}
+	}
+
+	if len(s.gauges) > 0 {
+		fmt.Fprintln(tw, "\t\t\t\t\t\t\t\t")
+		fmt.Fprintln(tw, "GAUGE\tATTRS\tVALUE\tTREND\t")
+		for _, series := range sortedSeries(s.gauges) {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n",
+				series.key.key, series.key.attrs, formatGauge(series.values[len(series.values)-1]), sparkline(series.values))
+		}
+	}
+
+	_ = tw.Flush() // writes to memory
+
+	return b.Bytes()
+}
+
+func sortedSeries(m map[dimensionKey]*terminalSeries) []*terminalSeries {
+	series := make([]*terminalSeries, 0, len(m))
+	for _, s := range m {
+		series = append(series, s)
+	}
+
+	slices.SortFunc(series, func(a, b *terminalSeries) int {
+		return cmp.Or(cmp.Compare(a.key.key, b.key.key), cmp.Compare(a.key.attrs, b.key.attrs))
+	})
+
+	return series
+}
+
+func appendHistory(history []float64, v float64) []float64 {
+	history = append(history, v)
+	if len(history) > terminalHistory {
+		history = history[len(history)-terminalHistory:]
+	}
+
+	return history
+}
+
+// sparkline scales values from zero to the maximum
+func sparkline(values []float64) string {
+	maxValue := 0.0
+	for _, v := range values {
+		maxValue = max(maxValue, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if maxValue > 0 {
+			i = int(v / maxValue * float64(len(sparkRunes)-1))
+		}
+
+		b.WriteRune(sparkRunes[min(max(i, 0), len(sparkRunes)-1)])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSparkline(t *testing.T) {
+	assert.Equal(t, "▁▄█", sparkline([]float64{0, 5, 10}))
+	assert.Equal(t, "▁▁", sparkline([]float64{0, 0}))
+	assert.Equal(t, "", sparkline(nil))
+}
+
+func TestTerminalSink(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := NewTerminalSink(out, "db.")
+
+	w := testWindow()
+	w.Gauges = []GaugeValue{
+		{Name: "db.in_use", Attrs: []Attr{String("db", "main")}, Value: 25},
+		{Name: "runtime.goroutines", Value: 100},
+	}
+
+	require.NoError(t, s.Write(w))
+	require.NoError(t, s.Write(w))
+	require.NoError(t, s.Close())
+
+	frames := strings.Split(out.String(), terminalClear)
+	require.Len(t, frames, 3)
+
+	last := frames[2]
+	assert.Contains(t, last, "SPAN")
+	assert.Contains(t, last, "db.in_use")
+	assert.Contains(t, last, "db=main")
+	assert.NotContains(t, last, "runtime.goroutines")
+
+	for _, stats := range w.Spans {
+		assert.Contains(t, last, stats.Key)
+	}
+
+	// two windows with the same p99 draw a flat line
+	assert.Contains(t, last, "██")
+	assert.Contains(t, last, formatLatency(w.Spans[0].Latency.Quantile(0.99)))
+}
+
+func TestTerminalSinkStalledKey(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := NewTerminalSink(out)
+
+	w := testWindow()
+	require.NoError(t, s.Write(w))
+
+	// UserDashboard stops completing
+	stalled := testWindow()
+	stalled.Spans = stalled.Spans[:2]
+	require.NoError(t, s.Write(stalled))
+
+	series := s.spans[dimensionKey{key: "UserDashboard"}]
+	assert.True(t, series.idle)
+	assert.Zero(t, series.rps)
+	assert.Equal(t, []float64{float64(40 * time.Millisecond), 0}, series.p99)
+
+	frames := strings.Split(out.String(), terminalClear)
+	line := frames[2][strings.Index(frames[2], "UserDashboard"):]
+	assert.Contains(t, line[:strings.Index(line, "\n")], "0.0")
+}
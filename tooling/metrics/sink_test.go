@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readCSVRows(t *testing.T, data string) []map[string]string {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+	require.Equal(t, csvHeader, records[0])
+
+	var rows []map[string]string
+	for _, record := range records[1:] {
+		row := map[string]string{}
+		for i, column := range records[0] {
+			row[column] = record[i]
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func testWindow() *Window {
+	now := time.UnixMilli(1_700_000_000_000)
+
+	results := []result{
+		{key: "GetArticleFeed", start: now, duration: 10 * time.Millisecond, attrs: []Attr{String("cache", "hit"), String("strategy", "cached")}},
+		{key: "GetArticleFeed", start: now, duration: 30 * time.Millisecond, attrs: []Attr{String("cache", "miss")}},
+		{key: "UserDashboard", start: now, duration: 40 * time.Millisecond},
+	}
+
+	return newWindow(results, newDimensions(defaultAttrSetsLimit), nil, 0, now.Add(-time.Second), now)
+}
+
+func TestCSVSink(t *testing.T) {
+	out := &bytes.Buffer{}
+	sink := NewCSVSink(out)
+
+	require.NoError(t, sink.Write(testWindow()))
+	require.NoError(t, sink.Write(testWindow()))
+	require.NoError(t, sink.Close())
+
+	rows := readCSVRows(t, out.String())
+	require.Len(t, rows, 6)
+	assert.Equal(t, strconv.Itoa(SchemaVersion), rows[0]["schema_version"])
+	assert.Equal(t, "cache=hit;strategy=cached", rows[0]["attrs"])
+	assert.Equal(t, "1700000000000", rows[0]["ts"])
+	assert.Equal(t, "1699999999000", rows[0]["window_start"])
+	assert.Equal(t, "1700000000000", rows[0]["window_end"])
+	assert.Equal(t, "UserDashboard", rows[2]["key"])
+	assert.Equal(t, "40000000", rows[2]["p99_ns"])
+}
+
+func TestJSONLSink(t *testing.T) {
+	out := &bytes.Buffer{}
+	sink := NewJSONLSink(out)
+
+	require.NoError(t, sink.Write(testWindow()))
+	require.NoError(t, sink.Close())
+
+	var w windowJSON
+	require.NoError(t, json.Unmarshal(out.Bytes(), &w))
+
+	assert.Equal(t, SchemaVersion, w.SchemaVersion)
+	require.Len(t, w.Spans, 3)
+	assert.Equal(t, []Attr{String("cache", "miss")}, w.Spans[1].Attrs)
+	assert.Equal(t, 30*time.Millisecond, w.Spans[1].Latency.P99)
+}
+
+func TestOpenMetricsSink(t *testing.T) {
+	out := &bytes.Buffer{}
+	sink := NewOpenMetricsSink(out)
+
+	require.NoError(t, sink.Write(testWindow()))
+	require.NoError(t, sink.Write(testWindow()))
+	require.NoError(t, sink.Close())
+
+	text := out.String()
+	assert.True(t, strings.HasSuffix(text, "# EOF\n"))
+	assert.Equal(t, 1, strings.Count(text, "# TYPE trainer_span_count gauge\n"))
+	assert.Contains(t, text, `trainer_span_count{span="GetArticleFeed",cache="hit",strategy="cached"} 1 1700000000.000`+"\n")
+	assert.Contains(t, text, `trainer_span_latency_seconds{span="UserDashboard",quantile="0.99"} 0.04 1700000000.000`+"\n")
+
+	// series points stay contiguous: both windows of a series go one after another
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, `trainer_span_count{span="UserDashboard"}`) {
+			assert.True(t, strings.HasPrefix(lines[i+1], `trainer_span_count{span="UserDashboard"}`))
+			break
+		}
+	}
+}
+
+func TestOpenMetricsSinkSpill(t *testing.T) {
+	write := func(chunkPoints int) string {
+		out := &bytes.Buffer{}
+		sink := NewOpenMetricsSink(out)
+		sink.chunkPoints = chunkPoints
+
+		for range 5 {
+			require.NoError(t, sink.Write(testWindow()))
+		}
+		require.NoError(t, sink.Close())
+
+		return out.String()
+	}
+
+	// spilled chunks are assembled back into the same contiguous series
+	assert.Equal(t, write(omChunkPoints), write(2))
+}
+
+func TestMultipleSinks(t *testing.T) {
+	csvOut, jsonOut := &bytes.Buffer{}, &bytes.Buffer{}
+
+	o := New(csvOut, WithSinks(NewJSONLSink(jsonOut)))
+	o.StartSpan("op").Done(nil)
+	require.NoError(t, o.Close())
+
+	assert.Len(t, readCSVRows(t, csvOut.String()), 1)
+	assert.Equal(t, 1, strings.Count(jsonOut.String(), "\n"))
+}
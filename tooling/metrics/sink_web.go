@@ -0,0 +1,212 @@
+package metrics
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	webHistory        = 3600
+	webClientBuffer   = 64
+	webCloseTimeout   = 5 * time.Second
+	webEventWindow    = "window"
+	webEventEnd       = "end"
+	webContentType    = "text/html; charset=utf-8"
+	webSSEContentType = "text/event-stream"
+)
+
+//go:embed web/index.html
+var webIndex []byte
+
+// WebSink serves a self-contained page with live charts per span key.
+// Windows are streamed to browsers as server-sent events, a new connection gets the whole run first.
+type WebSink struct {
+	server   *http.Server
+	listener net.Listener
+	// served gets the server failure, Close reports it
+	served chan error
+
+	mu      sync.Mutex
+	history [][]byte
+	clients map[chan []byte]struct{}
+	closed  bool
+}
+
+// NewWebSink starts listening on addr, ":0" picks a free port
+func NewWebSink(addr string) (*WebSink, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error listening web addr: %v", err)
+	}
+
+	s := &WebSink{
+		listener: listener,
+		clients:  map[chan []byte]struct{}{},
+		served:   make(chan error, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.index)
+	mux.HandleFunc("/events", s.events)
+
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		s.served <- serve(s.server, listener)
+	}()
+
+	return s, nil
+}
+
+func (s *WebSink) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Write never waits for browsers, a client that can't keep up is disconnected and replays the run on reconnect
+func (s *WebSink) Write(w *Window) error {
+	data, err := json.Marshal(newWindowJSON(w))
+	if err != nil {
+		return fmt.Errorf("failed to encode window: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history = append(s.history, data)
+	if len(s.history) > webHistory {
+		s.history = s.history[len(s.history)-webHistory:]
+	}
+
+	for c := range s.clients {
+		select {
+		case c <- data:
+		default:
+			delete(s.clients, c)
+			close(c)
+		}
+	}
+
+	return nil
+}
+
+// Close tells browsers the run is over and stops the server, it also returns the error the server failed with
+func (s *WebSink) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.clients {
+		delete(s.clients, c)
+		close(c)
+	}
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), webCloseTimeout)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to stop web server: %v", err)
+	}
+
+	if err := <-s.served; err != nil {
+		return fmt.Errorf("web server failed: %v", err)
+	}
+
+	return nil
+}
+
+func (s *WebSink) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", webContentType)
+	_, _ = w.Write(webIndex)
+}
+
+// subscribe returns windows written so far and a channel of the next ones, nil channel after Close
+func (s *WebSink) subscribe() ([][]byte, chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := s.history[:len(s.history):len(s.history)]
+	if s.closed {
+		return history, nil
+	}
+
+	c := make(chan []byte, webClientBuffer)
+	s.clients[c] = struct{}{}
+
+	return history, c
+}
+
+func (s *WebSink) unsubscribe(c chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.clients[c]; ok {
+		delete(s.clients, c)
+		close(c)
+	}
+}
+
+func (s *WebSink) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", webSSEContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	history, c := s.subscribe()
+	if c != nil {
+		defer s.unsubscribe(c)
+	}
+
+	for _, data := range history {
+		writeEvent(w, webEventWindow, data)
+	}
+	flusher.Flush()
+
+	if c == nil {
+		writeEvent(w, webEventEnd, []byte("{}"))
+		flusher.Flush()
+		return
+	}
+
+	for {
+		select {
+		case data, ok := <-c:
+			if !ok {
+				s.mu.Lock()
+				closed := s.closed
+				s.mu.Unlock()
+
+				// a slow client is just dropped, the browser reconnects by itself
+				if closed {
+					writeEvent(w, webEventEnd, []byte("{}"))
+					flusher.Flush()
+				}
+
+				return
+			}
+
+			writeEvent(w, webEventWindow, data)
+			flusher.Flush()
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, data []byte) {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSinkIndex(t *testing.T) {
+	s, err := NewWebSink("127.0.0.1:0")
+	require.NoError(t, err)
+	defer s.Close()
+
+	resp, err := http.Get("http://" + s.Addr() + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	page, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(page), `new EventSource("events")`)
+	// offline page, no external assets
+	assert.NotContains(t, string(page), "src=")
+	assert.NotContains(t, string(page), "href=")
+}
+
+func TestWebSinkEvents(t *testing.T) {
+	s, err := NewWebSink("127.0.0.1:0")
+	require.NoError(t, err)
+
+	w := testWindow()
+	require.NoError(t, s.Write(w))
+
+	resp, err := http.Get("http://" + s.Addr() + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, webSSEContentType, resp.Header.Get("Content-Type"))
+
+	events := bufio.NewReader(resp.Body)
+
+	// replayed history
+	event, data := readEvent(t, events)
+	assert.Equal(t, webEventWindow, event)
+
+	var j windowJSON
+	require.NoError(t, json.Unmarshal([]byte(data), &j))
+	assert.Len(t, j.Spans, len(w.Spans))
+
+	// live window
+	require.NoError(t, s.Write(w))
+	event, _ = readEvent(t, events)
+	assert.Equal(t, webEventWindow, event)
+
+	require.NoError(t, s.Close())
+	event, _ = readEvent(t, events)
+	assert.Equal(t, webEventEnd, event)
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) (event, data string) {
+	t.Helper()
+
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
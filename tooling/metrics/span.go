@@ -1,28 +1,90 @@
 package metrics
 
-import "time"
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// spanContextKey is per pipeline, so pipelines combined by Multi see only their own parents
+type spanContextKey struct {
+	obs *Observability
+}
 
 type span struct {
-	key         string
-	start       time.Time
-	metricsChan metricChan
+	key      string
+	attrs    []Attr
+	args     []Attr
+	start    time.Time
+	intended time.Time
+	obs      *Observability
+	id       SpanID
+	parentID SpanID
+	trace    *traceState
 }
 
-func newSpan(key string, metricChan metricChan) *span {
-	return &span{
-		key:         key,
-		start:       time.Now(),
-		metricsChan: metricChan,
+func newSpan(ctx context.Context, key string, attrs []Attr, obs *Observability) *span {
+	s := &span{
+		key:   key,
+		attrs: slices.Clip(attrs),
+		start: time.Now(),
+		obs:   obs,
+		id:    newSpanID(),
+	}
+
+	if t, ok := IntendedStart(ctx); ok {
+		s.intended = t
 	}
+
+	if parent, ok := ctx.Value(spanContextKey{obs: obs}).(*span); ok {
+		s.parentID = parent.id
+		s.trace = parent.trace
+	} else {
+		s.trace = &traceState{id: newTraceID()}
+	}
+
+	return s
+}
+
+func (s *span) SetAttributes(attrs ...Attr) {
+	s.attrs = append(s.attrs, attrs...)
+}
+
+func (s *span) SetArgs(args ...Attr) {
+	s.args = append(s.args, args...)
 }
 
 func (s *span) Done(err error) {
 	r := result{
 		key:      s.key,
 		start:    s.start,
-		duration: s.start.Sub(time.Now()),
+		intended: s.intended,
+		duration: time.Since(s.start),
 		err:      err,
+		attrs:    s.attrs,
+		args:     s.args,
+		traceID:  s.trace.id,
+		spanID:   s.id,
+	}
+
+	record := SpanRecord{
+		SpanID:   s.id,
+		ParentID: s.parentID,
+		Name:     s.key,
+		Start:    r.start,
+		Duration: r.duration,
+		Attrs:    s.attrs,
+		Args:     s.args,
+	}
+	if err != nil {
+		record.Err = err.Error()
+	}
+
+	if s.parentID.IsZero() {
+		r.trace = s.trace.finish(record)
+	} else {
+		s.trace.add(record)
 	}
 
-	s.metricsChan <- r
+	s.obs.record(r)
 }
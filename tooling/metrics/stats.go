@@ -0,0 +1,155 @@
+package metrics
+
+import "math"
+
+type sampleStats struct {
+	n        int
+	mean     float64
+	variance float64
+}
+
+func newSampleStats(values []float64) sampleStats {
+	s := sampleStats{n: len(values)}
+	if s.n == 0 {
+		return s
+	}
+
+	for _, v := range values {
+		s.mean += v
+	}
+	s.mean /= float64(s.n)
+
+	if s.n < 2 {
+		return s
+	}
+
+	for _, v := range values {
+		s.variance += (v - s.mean) * (v - s.mean)
+	}
+	s.variance /= float64(s.n - 1)
+
+	return s
+}
+
+// welchTest returns the difference of means b-a, its confidence interval for given confidence level
+// and two-sided p-value of Welch's t-test. P-value is NaN when a sample has less than two values.
+func welchTest(a, b sampleStats, confidence float64) (diff, low, high, p float64) {
+	diff = b.mean - a.mean
+
+	if a.n < 2 || b.n < 2 {
+		return diff, math.NaN(), math.NaN(), math.NaN()
+	}
+
+	va, vb := a.variance/float64(a.n), b.variance/float64(b.n)
+	se := math.Sqrt(va + vb)
+
+	if se == 0 {
+		if diff == 0 {
+			return diff, diff, diff, 1
+		}
+
+		return diff, diff, diff, 0
+	}
+
+	df := (va + vb) * (va + vb) / (va*va/float64(a.n-1) + vb*vb/float64(b.n-1))
+	t := diff / se
+
+	p = studentTTwoSided(t, df)
+	margin := studentTQuantile(1-(1-confidence)/2, df) * se
+
+	return diff, diff - margin, diff + margin, p
+}
+
+// studentTTwoSided is P(|T| >= |t|) for Student's t distribution
+func studentTTwoSided(t, df float64) float64 {
+	return incompleteBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// studentTQuantile finds t with CDF(t) = q for q > 0.5 by bisection
+func studentTQuantile(q, df float64) float64 {
+	low, high := 0.0, 1000.0
+	for range 100 {
+		mid := (low + high) / 2
+		if 1-studentTTwoSided(mid, df)/2 < q {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	return (low + high) / 2
+}
+
+// incompleteBeta is the regularized incomplete beta function I_x(a, b)
+func incompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+// betaContinuedFraction evaluates the continued fraction of incomplete beta with modified Lentz's method
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+
+		// even step
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		// odd step
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+
+	return h
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStudentT(t *testing.T) {
+	// reference values from t tables
+	assert.InDelta(t, 0.05, studentTTwoSided(2.228, 10), 1e-3)
+	assert.InDelta(t, 0.01, studentTTwoSided(2.576, 1e6), 1e-3)
+	assert.InDelta(t, 1.0, studentTTwoSided(0, 5), 1e-9)
+
+	assert.InDelta(t, 2.086, studentTQuantile(0.975, 20), 1e-3)
+	assert.InDelta(t, 1.960, studentTQuantile(0.975, 1e6), 1e-3)
+}
+
+func TestWelchTest(t *testing.T) {
+	a := newSampleStats([]float64{10, 11, 9, 10, 10, 11, 9, 10})
+	b := newSampleStats([]float64{15, 16, 14, 15, 15, 16, 14, 15})
+
+	diff, low, high, p := welchTest(a, b, 0.95)
+	assert.InDelta(t, 5, diff, 1e-9)
+	assert.Less(t, low, diff)
+	assert.Greater(t, high, diff)
+	assert.Less(t, p, 0.001)
+
+	_, _, _, p = welchTest(a, a, 0.95)
+	assert.InDelta(t, 1, p, 1e-9)
+
+	_, _, _, p = welchTest(newSampleStats([]float64{1}), b, 0.95)
+	assert.True(t, math.IsNaN(p))
+}
@@ -0,0 +1,315 @@
+package metrics
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Summary accumulates windows into whole-run numbers per span key and attribute set
+type Summary struct {
+	Start    time.Time
+	End      time.Time
+	Keys     []*KeySummary
+	Counters []*CounterSummary
+	Gauges   []*GaugeSummary
+
+	byKey     map[dimensionKey]*KeySummary
+	byCounter map[dimensionKey]*CounterSummary
+	byGauge   map[dimensionKey]*GaugeSummary
+}
+
+type KeySummary struct {
+	Key    string
+	Attrs  string
+	Total  *SpanStats
+	Points []SummaryPoint
+	// Best and Worst are windows with the lowest and the highest p99
+	Best  SummaryPoint
+	Worst SummaryPoint
+}
+
+// CounterSummary is the total increase of a counter over the run
+type CounterSummary struct {
+	Name  string
+	Attrs string
+	Total int64
+}
+
+// GaugeSummary is the range of a sampled gauge over the run
+type GaugeSummary struct {
+	Name  string
+	Attrs string
+	Min   float64
+	Max   float64
+	Sum   float64
+	Count int
+	Last  float64
+}
+
+func (g *GaugeSummary) Mean() float64 {
+	if g.Count == 0 {
+		return 0
+	}
+
+	return g.Sum / float64(g.Count)
+}
+
+func (g *GaugeSummary) add(v float64) {
+	if g.Count == 0 || v < g.Min {
+		g.Min = v
+	}
+	if g.Count == 0 || v > g.Max {
+		g.Max = v
+	}
+
+	g.Sum += v
+	g.Count++
+	g.Last = v
+}
+
+// SummaryPoint is one window of a key
+type SummaryPoint struct {
+	Time      time.Time
+	Count     int64
+	RPS       float64
+	P99       time.Duration
+	ErrorRate float64
+}
+
+func NewSummary() *Summary {
+	return &Summary{
+		byKey:     map[dimensionKey]*KeySummary{},
+		byCounter: map[dimensionKey]*CounterSummary{},
+		byGauge:   map[dimensionKey]*GaugeSummary{},
+	}
+}
+
+// Add accounts a window. Sliding windows overlap, only their latest part goes to totals,
+// while points show the smoothed values.
+func (s *Summary) Add(w *Window) {
+	totals := w
+	if w.latest != nil {
+		totals = w.latest
+	}
+
+	if s.Start.IsZero() || totals.Start.Before(s.Start) {
+		s.Start = totals.Start
+	}
+	if w.Time.After(s.End) {
+		s.End = w.Time
+	}
+
+	for _, stats := range totals.Spans {
+		s.key(stats).Total.Merge(stats)
+	}
+
+	for _, stats := range w.Spans {
+		if stats.Count == 0 {
+			continue
+		}
+
+		ks := s.key(stats)
+
+		p := SummaryPoint{
+			Time:      w.Time,
+			Count:     stats.Count,
+			RPS:       rate(stats.Count, w.Duration()),
+			P99:       stats.Latency.Quantile(0.99),
+			ErrorRate: stats.ErrorRate(),
+		}
+
+		if len(ks.Points) == 0 || p.P99 < ks.Best.P99 {
+			ks.Best = p
+		}
+		if len(ks.Points) == 0 || p.P99 > ks.Worst.P99 {
+			ks.Worst = p
+		}
+
+		ks.Points = append(ks.Points, p)
+	}
+
+	for _, c := range totals.Counters {
+		k := dimensionKey{key: c.Name, attrs: formatAttrs(c.Attrs)}
+
+		cs, ok := s.byCounter[k]
+		if !ok {
+			cs = &CounterSummary{Name: k.key, Attrs: k.attrs}
+			s.byCounter[k] = cs
+			s.Counters = append(s.Counters, cs)
+			slices.SortFunc(s.Counters, func(a, b *CounterSummary) int {
+				return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Attrs, b.Attrs))
+			})
+		}
+
+		cs.Total += c.Value
+	}
+
+	for _, g := range w.Gauges {
+		k := dimensionKey{key: g.Name, attrs: formatAttrs(g.Attrs)}
+
+		gs, ok := s.byGauge[k]
+		if !ok {
+			gs = &GaugeSummary{Name: k.key, Attrs: k.attrs}
+			s.byGauge[k] = gs
+			s.Gauges = append(s.Gauges, gs)
+			slices.SortFunc(s.Gauges, func(a, b *GaugeSummary) int {
+				return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Attrs, b.Attrs))
+			})
+		}
+
+		gs.add(g.Value)
+	}
+}
+
+func (s *Summary) key(stats *SpanStats) *KeySummary {
+	k := dimensionKey{key: stats.Key, attrs: stats.AttrsString()}
+
+	ks, ok := s.byKey[k]
+	if !ok {
+		ks = &KeySummary{
+			Key:   k.key,
+			Attrs: k.attrs,
+			Total: newSpanStats(stats.Key, stats.Attrs, stats.Timestamp),
+		}
+		s.byKey[k] = ks
+		s.Keys = append(s.Keys, ks)
+		slices.SortFunc(s.Keys, func(a, b *KeySummary) int {
+			return cmp.Or(cmp.Compare(a.Key, b.Key), cmp.Compare(a.Attrs, b.Attrs))
+		})
+	}
+
+	return ks
+}
+
+func (s *Summary) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Throughput is requests per second over the whole run
+func (s *Summary) Throughput(ks *KeySummary) float64 {
+	return rate(ks.Total.Count, s.Duration())
+}
+
+func rate(count int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+
+	return float64(count) / d.Seconds()
+}
+
+func formatLatency(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
+
+func (s *Summary) WriteMarkdown(w io.Writer) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, "# Load test summary\n\n")
+	fmt.Fprintf(b, "Run: %s - %s (%s)\n\n",
+		s.Start.Format(time.DateTime), s.End.Format(time.DateTime), s.Duration().Round(time.Second))
+
+	fmt.Fprintf(b, "| Span | Attrs | Count | RPS | Errors | Error rate | p50 | p90 | p95 | p99 | p99.9 | Max | Mean | Response p99 | Best window p99 | Worst window p99 |\n")
+	fmt.Fprintf(b, "|---|---|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|---|---|\n")
+
+	for _, ks := range s.Keys {
+		l := ks.Total.Latency.Summary()
+
+		fmt.Fprintf(b, "| %s | %s | %d | %.1f | %d | %.2f%% | %s | %s | %s | %s | %s | %s | %s | %s | %s at %s | %s at %s |\n",
+			ks.Key, ks.Attrs, ks.Total.Count, s.Throughput(ks), ks.Total.Errors, ks.Total.ErrorRate()*100,
+			formatLatency(l.P50), formatLatency(l.P90), formatLatency(l.P95), formatLatency(l.P99),
+			formatLatency(l.P999), formatLatency(l.Max), formatLatency(l.Mean),
+			formatLatency(ks.Total.Response.Quantile(0.99)),
+			formatLatency(ks.Best.P99), s.offset(ks.Best.Time),
+			formatLatency(ks.Worst.P99), s.offset(ks.Worst.Time),
+		)
+	}
+
+	if len(s.Counters) > 0 {
+		fmt.Fprintf(b, "\n## Counters\n\n")
+		fmt.Fprintf(b, "| Counter | Attrs | Total | Per second |\n")
+		fmt.Fprintf(b, "|---|---|---:|---:|\n")
+
+		for _, c := range s.Counters {
+			fmt.Fprintf(b, "| %s | %s | %d | %.1f |\n", c.Name, c.Attrs, c.Total, rate(c.Total, s.Duration()))
+		}
+	}
+
+	if len(s.Gauges) > 0 {
+		fmt.Fprintf(b, "\n## Gauges\n\n")
+		fmt.Fprintf(b, "| Gauge | Attrs | Min | Mean | Max | Last |\n")
+		fmt.Fprintf(b, "|---|---|---:|---:|---:|---:|\n")
+
+		for _, g := range s.Gauges {
+			fmt.Fprintf(b, "| %s | %s | %s | %s | %s | %s |\n",
+				g.Name, g.Attrs, formatGauge(g.Min), formatGauge(g.Mean()), formatGauge(g.Max), formatGauge(g.Last))
+		}
+	}
+
+	if err := b.Flush(); err != nil {
+		return fmt.Errorf("failed to write markdown summary: %v", err)
+	}
+
+	return nil
+}
+
+func formatGauge(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+// offset formats window time relative to the run start
+func (s *Summary) offset(t time.Time) string {
+	return "+" + t.Sub(s.Start).Round(time.Second).String()
+}
+
+// SummarySink writes the run summary on Close, nil writers are skipped
+type SummarySink struct {
+	summary  *Summary
+	markdown io.Writer
+	html     io.Writer
+}
+
+func NewSummarySink(markdown, html io.Writer) *SummarySink {
+	return &SummarySink{
+		summary:  NewSummary(),
+		markdown: markdown,
+		html:     html,
+	}
+}
+
+func (s *SummarySink) Summary() *Summary {
+	return s.summary
+}
+
+func (s *SummarySink) Write(w *Window) error {
+	s.summary.Add(w)
+
+	return nil
+}
+
+func (s *SummarySink) Close() error {
+	var errs []error
+
+	if s.markdown != nil {
+		errs = append(errs, s.summary.WriteMarkdown(s.markdown), closeWriter(s.markdown))
+	}
+
+	if s.html != nil {
+		errs = append(errs, s.summary.WriteHTML(s.html), closeWriter(s.html))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth   = 640
+	chartHeight  = 180
+	chartPadding = 40
+)
+
+var summaryHTML = template.Must(template.New("summary").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Load test summary</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child, th:nth-child(2), td:nth-child(2) { text-align: left; }
+.charts { display: flex; flex-wrap: wrap; gap: 1em; }
+svg { background: #fafafa; border: 1px solid #ddd; }
+svg text { font-size: 11px; fill: #555; }
+</style>
+</head>
+<body>
+<h1>Load test summary</h1>
+<p>Run: {{.Start}} - {{.End}} ({{.Duration}})</p>
+<table>
+<tr><th>Span</th><th>Attrs</th><th>Count</th><th>RPS</th><th>Errors</th><th>Error rate</th><th>p50</th><th>p90</th><th>p95</th><th>p99</th><th>p99.9</th><th>Max</th><th>Mean</th><th>Response p99</th><th>Best window p99</th><th>Worst window p99</th></tr>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{if .Counters}}<h2>Counters</h2>
+<table>
+<tr><th>Counter</th><th>Attrs</th><th>Total</th><th>Per second</th></tr>
+{{range .Counters}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}{{if .Gauges}}<h2>Gauges</h2>
+<table>
+<tr><th>Gauge</th><th>Attrs</th><th>Min</th><th>Mean</th><th>Max</th><th>Last</th></tr>
+{{range .Gauges}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}{{range .Charts}}<h2>{{.Title}}</h2>
+<div class="charts">{{.RPS}}{{.P99}}</div>
+{{end}}</body>
+</html>
+`))
+
+type summaryChart struct {
+	Title string
+	RPS   template.HTML
+	P99   template.HTML
+}
+
+// WriteHTML writes a self-contained page with the summary table and SVG charts of RPS and p99 per key
+func (s *Summary) WriteHTML(w io.Writer) error {
+	data := struct {
+		Start    string
+		End      string
+		Duration string
+		Rows     [][]string
+		Counters [][]string
+		Gauges   [][]string
+		Charts   []summaryChart
+	}{
+		Start:    s.Start.Format(time.DateTime),
+		End:      s.End.Format(time.DateTime),
+		Duration: s.Duration().Round(time.Second).String(),
+	}
+
+	for _, ks := range s.Keys {
+		l := ks.Total.Latency.Summary()
+
+		data.Rows = append(data.Rows, []string{
+			ks.Key, ks.Attrs,
+			strconv.FormatInt(ks.Total.Count, 10),
+			fmt.Sprintf("%.1f", s.Throughput(ks)),
+			strconv.FormatInt(ks.Total.Errors, 10),
+			fmt.Sprintf("%.2f%%", ks.Total.ErrorRate()*100),
+			formatLatency(l.P50), formatLatency(l.P90), formatLatency(l.P95), formatLatency(l.P99),
+			formatLatency(l.P999), formatLatency(l.Max), formatLatency(l.Mean),
+			formatLatency(ks.Total.Response.Quantile(0.99)),
+			formatLatency(ks.Best.P99) + " at " + s.offset(ks.Best.Time),
+			formatLatency(ks.Worst.P99) + " at " + s.offset(ks.Worst.Time),
+		})
+
+		title := ks.Key
+		if ks.Attrs != "" {
+			title += " {" + ks.Attrs + "}"
+		}
+
+		rps := make([]float64, len(ks.Points))
+		p99 := make([]float64, len(ks.Points))
+		times := make([]time.Duration, len(ks.Points))
+		for i, p := range ks.Points {
+			rps[i] = p.RPS
+			p99[i] = float64(p.P99) / float64(time.Millisecond)
+			times[i] = p.Time.Sub(s.Start)
+		}
+
+		data.Charts = append(data.Charts, summaryChart{
+			Title: title,
+			RPS:   svgLineChart("RPS", "", times, rps, s.Duration()),
+			P99:   svgLineChart("p99", "ms", times, p99, s.Duration()),
+		})
+	}
+
+	for _, c := range s.Counters {
+		data.Counters = append(data.Counters, []string{
+			c.Name, c.Attrs, strconv.FormatInt(c.Total, 10), fmt.Sprintf("%.1f", rate(c.Total, s.Duration())),
+		})
+	}
+
+	for _, g := range s.Gauges {
+		data.Gauges = append(data.Gauges, []string{
+			g.Name, g.Attrs, formatGauge(g.Min), formatGauge(g.Mean()), formatGauge(g.Max), formatGauge(g.Last),
+		})
+	}
+
+	if err := summaryHTML.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to write html summary: %v", err)
+	}
+
+	return nil
+}
+
+// svgLineChart draws values over run time, y axis starts from zero
+func svgLineChart(title, unit string, times []time.Duration, values []float64, total time.Duration) template.HTML {
+	plotW := float64(chartWidth - 2*chartPadding)
+	plotH := float64(chartHeight - 2*chartPadding)
+
+	maxValue := 0.0
+	for _, v := range values {
+		maxValue = max(maxValue, v)
+	}
+	if maxValue == 0 {
+		maxValue = 1
+	}
+
+	x := func(t time.Duration) float64 {
+		if total <= 0 {
+			return chartPadding
+		}
+
+		return chartPadding + plotW*float64(t)/float64(total)
+	}
+	y := func(v float64) float64 {
+		return chartPadding + plotH - plotH*v/maxValue
+	}
+
+	var points strings.Builder
+	for i, v := range values {
+		fmt.Fprintf(&points, "%.1f,%.1f ", x(times[i]), y(v))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="20">%s</text>`, chartPadding, template.HTMLEscapeString(title))
+	fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#999"/>`,
+		chartPadding, y(0), chartPadding+plotW, y(0))
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%.1f" stroke="#999"/>`,
+		chartPadding, chartPadding, chartPadding, y(0))
+	fmt.Fprintf(&b, `<text x="2" y="%d">%s</text>`, chartPadding+4, template.HTMLEscapeString(formatAxisValue(maxValue, unit)))
+	fmt.Fprintf(&b, `<text x="2" y="%.1f">0</text>`, y(0)+4)
+	fmt.Fprintf(&b, `<text x="%d" y="%d">0s</text>`, chartPadding, chartHeight-chartPadding/2)
+	fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="end">%s</text>`,
+		chartPadding+plotW, chartHeight-chartPadding/2, total.Round(time.Second))
+	fmt.Fprintf(&b, `<polyline fill="none" stroke="#2a6fdb" stroke-width="1.5" points="%s"/>`, strings.TrimSpace(points.String()))
+	b.WriteString(`</svg>`)
+
+	return template.HTML(b.String())
+}
+
+func formatAxisValue(v float64, unit string) string {
+	return strconv.FormatFloat(v, 'g', 3, 64) + unit
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func summaryWindow(start time.Time, durations ...time.Duration) *Window {
+	var results []result
+	for _, d := range durations {
+		results = append(results, result{key: "UserDashboard", start: start, duration: d})
+	}
+	results = append(results, result{key: "UserDashboard", start: start, duration: time.Millisecond, err: context.DeadlineExceeded})
+
+	return newWindow(results, newDimensions(defaultAttrSetsLimit), nil, 0, start, start.Add(time.Second))
+}
+
+func TestSummary(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	s := NewSummary()
+	s.Add(summaryWindow(start, 10*time.Millisecond, 10*time.Millisecond, 10*time.Millisecond))
+	s.Add(summaryWindow(start.Add(time.Second), 100*time.Millisecond))
+	s.Add(summaryWindow(start.Add(2*time.Second), 20*time.Millisecond))
+
+	require.Len(t, s.Keys, 1)
+	ks := s.Keys[0]
+
+	assert.Equal(t, int64(8), ks.Total.Count)
+	assert.Equal(t, int64(3), ks.Total.Errors)
+	assert.InDelta(t, 8.0/3, s.Throughput(ks), 1e-9)
+	assert.Equal(t, 100*time.Millisecond, ks.Total.Latency.Max())
+	assert.Equal(t, 10*time.Millisecond, ks.Best.P99)
+	assert.Equal(t, 100*time.Millisecond, ks.Worst.P99)
+	assert.Equal(t, start.Add(2*time.Second), ks.Worst.Time)
+	assert.Len(t, ks.Points, 3)
+
+	md := &bytes.Buffer{}
+	require.NoError(t, s.WriteMarkdown(md))
+	assert.Contains(t, md.String(), "| UserDashboard |  | 8 | 2.7 | 3 | 37.50% |")
+	assert.Contains(t, md.String(), "100ms at +2s")
+
+	html := &bytes.Buffer{}
+	require.NoError(t, s.WriteHTML(html))
+	assert.Equal(t, 2, strings.Count(html.String(), "<svg "))
+	assert.Contains(t, html.String(), "<td>UserDashboard</td>")
+	assert.NotContains(t, html.String(), "src=", "page must not load external assets")
+	assert.NotContains(t, html.String(), "href=", "page must not load external assets")
+}
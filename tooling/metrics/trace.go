@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"encoding/hex"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+func newTraceID() (id TraceID) {
+	for i := range 2 {
+		v := rand.Uint64()
+		for j := range 8 {
+			id[i*8+j] = byte(v >> (8 * j))
+		}
+	}
+
+	return id
+}
+
+func newSpanID() (id SpanID) {
+	v := rand.Uint64() | 1 // never zero, zero means "no parent"
+	for j := range 8 {
+		id[j] = byte(v >> (8 * j))
+	}
+
+	return id
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id TraceID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *TraceID) UnmarshalText(text []byte) error {
+	_, err := hex.Decode(id[:], text)
+
+	return err
+}
+
+func (id SpanID) IsZero() bool {
+	return id == SpanID{}
+}
+
+func (id SpanID) String() string {
+	if id.IsZero() {
+		return ""
+	}
+
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *SpanID) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*id = SpanID{}
+		return nil
+	}
+
+	_, err := hex.Decode(id[:], text)
+
+	return err
+}
+
+// SpanRecord is a finished span of a trace
+type SpanRecord struct {
+	SpanID   SpanID        `json:"span_id"`
+	ParentID SpanID        `json:"parent_id"`
+	Name     string        `json:"name"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Err      string        `json:"error,omitempty"`
+	Attrs    []Attr        `json:"attrs,omitempty"`
+	Args     []Attr        `json:"args,omitempty"`
+}
+
+func (r SpanRecord) End() time.Time {
+	return r.Start.Add(r.Duration)
+}
+
+// Trace is a per-request record: the root span with all children finished before it
+type Trace struct {
+	TraceID TraceID      `json:"trace_id"`
+	Spans   []SpanRecord `json:"spans"`
+}
+
+func (t *Trace) Root() SpanRecord {
+	for _, s := range t.Spans {
+		if s.ParentID.IsZero() {
+			return s
+		}
+	}
+
+	return SpanRecord{}
+}
+
+func (t *Trace) Children(parent SpanID) []SpanRecord {
+	var children []SpanRecord
+	for _, s := range t.Spans {
+		if s.ParentID == parent {
+			children = append(children, s)
+		}
+	}
+
+	return children
+}
+
+// CriticalPath follows the latest finished child from the root down.
+// Children run concurrently, so the one that finished last is the one parent waited for.
+func (t *Trace) CriticalPath() []SpanRecord {
+	current := t.Root()
+	if current.SpanID.IsZero() {
+		return nil
+	}
+
+	path := []SpanRecord{current}
+	for {
+		children := t.Children(current.SpanID)
+		if len(children) == 0 {
+			return path
+		}
+
+		current = slices.MaxFunc(children, func(a, b SpanRecord) int {
+			return a.End().Compare(b.End())
+		})
+		path = append(path, current)
+	}
+}
+
+// traceState collects finished spans of one trace until the root is done
+type traceState struct {
+	id    TraceID
+	mu    sync.Mutex
+	spans []SpanRecord
+}
+
+func (t *traceState) add(r SpanRecord) {
+	t.mu.Lock()
+	t.spans = append(t.spans, r)
+	t.mu.Unlock()
+}
+
+func (t *traceState) finish(root SpanRecord) *Trace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spans := make([]SpanRecord, 0, len(t.spans)+1)
+	spans = append(spans, root)
+	spans = append(spans, t.spans...)
+
+	return &Trace{
+		TraceID: t.id,
+		Spans:   spans,
+	}
+}
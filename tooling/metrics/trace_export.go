@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// TraceExporter receives finished per-request traces once per window.
+// Export runs on the pipeline goroutine, a slow one holds windows and, under OverflowBlock, the tested code.
+type TraceExporter interface {
+	Export(traces []*Trace) error
+	Close() error
+}
+
+// jsonTraceExporter writes one Trace JSON object per line, the format ReadTraces reads
+type jsonTraceExporter struct {
+	w io.Writer
+}
+
+func (e *jsonTraceExporter) Export(traces []*Trace) error {
+	encoder := json.NewEncoder(e.w)
+	for _, t := range traces {
+		if err := encoder.Encode(t); err != nil {
+			return fmt.Errorf("failed to write traces to file: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (e *jsonTraceExporter) Close() error {
+	return closeWriter(e.w)
+}
@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanHierarchy(t *testing.T) {
+	o := New(&bytes.Buffer{})
+
+	ctx, root := o.Start(context.Background(), "UserDashboard")
+	_, feed := o.Start(ctx, "GetArticleFeed")
+	_, steps := o.Start(ctx, "GetLatestCarePlanSteps")
+
+	steps.Done(nil)
+	feed.Done(errors.New("boom"))
+	root.Done(nil)
+
+	var results []result
+	for range 3 {
+		results = append(results, <-o.metricsChan)
+	}
+
+	traces := collectTraces(results)
+	require.Len(t, traces, 1)
+
+	trace := traces[0]
+	require.Len(t, trace.Spans, 3)
+	assert.Equal(t, "UserDashboard", trace.Root().Name)
+	assert.Len(t, trace.Children(trace.Root().SpanID), 2)
+
+	path := trace.CriticalPath()
+	require.Len(t, path, 2)
+	assert.Equal(t, "GetArticleFeed", path[1].Name)
+	assert.Equal(t, "boom", path[1].Err)
+
+	encoded, err := json.Marshal(trace)
+	require.NoError(t, err)
+
+	decoded, err := ReadTraces(bytes.NewReader(encoded))
+	require.NoError(t, err)
+	require.Len(t, decoded, 1)
+	assert.Equal(t, trace.TraceID, decoded[0].TraceID)
+	decodedPath := decoded[0].CriticalPath()
+	require.Len(t, decodedPath, 2)
+	assert.Equal(t, path[1].SpanID, decodedPath[1].SpanID)
+	assert.True(t, path[1].Start.Equal(decodedPath[1].Start))
+}
+
+func TestMultiKeepsHierarchyPerPipeline(t *testing.T) {
+	a, b := New(&bytes.Buffer{}), New(&bytes.Buffer{})
+	m := Multi(a, b)
+
+	ctx, root := m.Start(context.Background(), "UserDashboard")
+	_, child := m.Start(ctx, "GetArticleFeed")
+	child.Done(nil)
+	root.Done(nil)
+
+	for _, o := range []*Observability{a, b} {
+		traces := collectTraces([]result{<-o.metricsChan, <-o.metricsChan})
+		require.Len(t, traces, 1)
+		require.Len(t, traces[0].Spans, 2)
+		assert.Equal(t, "UserDashboard", traces[0].Root().Name)
+		assert.Len(t, traces[0].Children(traces[0].Root().SpanID), 1)
+	}
+}
+
+func testTrace(rootDuration, feed, steps time.Duration) Trace {
+	start := time.Unix(0, 0)
+	root := SpanRecord{SpanID: newSpanID(), Name: "UserDashboard", Start: start, Duration: rootDuration}
+
+	return Trace{
+		TraceID: newTraceID(),
+		Spans: []SpanRecord{
+			root,
+			{SpanID: newSpanID(), ParentID: root.SpanID, Name: "GetArticleFeed", Start: start, Duration: feed},
+			{SpanID: newSpanID(), ParentID: root.SpanID, Name: "GetLatestCarePlanSteps", Start: start, Duration: steps},
+		},
+	}
+}
+
+func TestAnalyzeCriticalPath(t *testing.T) {
+	var traces []Trace
+	for range 90 {
+		traces = append(traces, testTrace(10*time.Millisecond, 5*time.Millisecond, 9*time.Millisecond))
+	}
+	for range 7 {
+		traces = append(traces, testTrace(100*time.Millisecond, 99*time.Millisecond, 9*time.Millisecond))
+	}
+	for range 3 {
+		traces = append(traces, testTrace(100*time.Millisecond, 9*time.Millisecond, 99*time.Millisecond))
+	}
+
+	stats := AnalyzeCriticalPath(traces, 0.9)
+	require.Len(t, stats, 1)
+
+	stat := stats[0]
+	assert.Equal(t, 100, stat.Total)
+	assert.Equal(t, 10, stat.Slow)
+	assert.Equal(t, BoundBy{Child: "GetArticleFeed", Count: 7, Share: 0.7}, stat.BoundBy[0])
+	assert.Contains(t, stat.String(), "70% of slow UserDashboard")
+}
@@ -0,0 +1,265 @@
+package metrics
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"time"
+)
+
+const (
+	defaultWindow = time.Second
+	minWindow     = 100 * time.Millisecond
+	maxWindow     = time.Minute
+)
+
+// Window is the aggregated result of one logging interval
+type Window struct {
+	// Start and Time are window boundaries, the first and the last windows of a run are partial
+	Start time.Time
+	Time  time.Time
+	Spans []*SpanStats
+	// Counters are increases of Obs counters within the window
+	Counters []CounterValue
+	// Gauges are the last values set through Obs and values polled from samplers at the end of the window
+	Gauges []GaugeValue
+	// Traces finished in the window, filled only when trace output is enabled
+	Traces []*Trace
+
+	// latest is the last not overlapping part of a sliding window
+	latest *Window
+}
+
+// SpanStats is aggregated data of one span key and attribute set
+type SpanStats struct {
+	Key   string
+	Attrs []Attr
+	// Timestamp is the latest span start
+	Timestamp time.Time
+	Count     int64
+	Success   int64
+	Errors    int64
+	Deadline  int64
+	Canceled  int64
+	SQL       int64
+	Other     int64
+	Dropped   int64
+	Sampled   int64
+	// Latency is service time from span start, Response is from intended start given by a load generator
+	Latency  *Histogram
+	Response *Histogram
+	// Exemplars are the slowest spans by service time, slowest first
+	Exemplars []Exemplar
+}
+
+func (w *Window) Duration() time.Duration {
+	return w.Time.Sub(w.Start)
+}
+
+// slide merges consecutive windows into one covering all of them, counters are summed up.
+// Gauges and traces are taken from the last window, they aren't aggregated over time.
+func slide(steps []*Window) *Window {
+	last := steps[len(steps)-1]
+
+	w := &Window{
+		Start:  steps[0].Start,
+		Time:   last.Time,
+		Gauges: last.Gauges,
+		Traces: last.Traces,
+		latest: last,
+	}
+
+	byKey := map[dimensionKey]*SpanStats{}
+	for _, step := range steps {
+		for _, s := range step.Spans {
+			k := dimensionKey{key: s.Key, attrs: s.AttrsString()}
+
+			merged, ok := byKey[k]
+			if !ok {
+				merged = newSpanStats(s.Key, s.Attrs, s.Timestamp)
+				byKey[k] = merged
+				w.Spans = append(w.Spans, merged)
+			}
+
+			merged.Merge(s)
+		}
+	}
+
+	slices.SortFunc(w.Spans, func(a, b *SpanStats) int {
+		return cmp.Or(cmp.Compare(a.Key, b.Key), cmp.Compare(a.AttrsString(), b.AttrsString()))
+	})
+
+	counters := map[dimensionKey]int{}
+	for _, step := range steps {
+		for _, c := range step.Counters {
+			k := dimensionKey{key: c.Name, attrs: formatAttrs(c.Attrs)}
+
+			i, ok := counters[k]
+			if !ok {
+				counters[k] = len(w.Counters)
+				w.Counters = append(w.Counters, c)
+				continue
+			}
+
+			w.Counters[i].Value += c.Value
+		}
+	}
+
+	slices.SortFunc(w.Counters, func(a, b CounterValue) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(formatAttrs(a.Attrs), formatAttrs(b.Attrs)))
+	})
+
+	return w
+}
+
+func newSpanStats(key string, attrs []Attr, ts time.Time) *SpanStats {
+	return &SpanStats{
+		Key:       key,
+		Attrs:     attrs,
+		Timestamp: ts,
+		Latency:   NewHistogram(),
+		Response:  NewHistogram(),
+	}
+}
+
+func (s *SpanStats) AttrsString() string {
+	return formatAttrs(s.Attrs)
+}
+
+func (s *SpanStats) ErrorRate() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+
+	return float64(s.Errors) / float64(s.Count)
+}
+
+func (s *SpanStats) add(r result) {
+	if r.start.After(s.Timestamp) {
+		s.Timestamp = r.start
+	}
+
+	n := max(r.weight, 1)
+
+	s.Count += n
+	s.Latency.RecordN(r.duration, uint64(n))
+	s.Response.RecordN(r.response(), uint64(n))
+
+	if r.err == nil {
+		s.Success += n
+		return
+	}
+
+	s.Errors += n
+
+	switch classifyError(r.err) {
+	case errDeadline:
+		s.Deadline += n
+	case errCanceled:
+		s.Canceled += n
+	case errSQL:
+		s.SQL += n
+	default:
+		s.Other += n
+	}
+}
+
+func (s *SpanStats) addExemplar(r result, limit int) {
+	if limit <= 0 {
+		return
+	}
+
+	if len(s.Exemplars) == limit && r.duration <= s.Exemplars[limit-1].Duration {
+		return
+	}
+
+	e := Exemplar{
+		TraceID:  r.traceID,
+		SpanID:   r.spanID,
+		Start:    r.start,
+		Duration: r.duration,
+		Response: r.response(),
+		Args:     r.args,
+	}
+	if r.err != nil {
+		e.Err = r.err.Error()
+	}
+
+	s.Exemplars = insertExemplar(s.Exemplars, e, limit)
+}
+
+func insertExemplar(exemplars []Exemplar, e Exemplar, limit int) []Exemplar {
+	i, _ := slices.BinarySearchFunc(exemplars, e.Duration, func(e Exemplar, d time.Duration) int {
+		return cmp.Compare(d, e.Duration)
+	})
+
+	exemplars = slices.Insert(exemplars, i, e)
+	if len(exemplars) > limit {
+		exemplars = exemplars[:limit]
+	}
+
+	return exemplars
+}
+
+// Merge folds another window of the same key, so windows can be summed up into run totals
+func (s *SpanStats) Merge(other *SpanStats) {
+	if other.Timestamp.After(s.Timestamp) {
+		s.Timestamp = other.Timestamp
+	}
+
+	s.Count += other.Count
+	s.Success += other.Success
+	s.Errors += other.Errors
+	s.Deadline += other.Deadline
+	s.Canceled += other.Canceled
+	s.SQL += other.SQL
+	s.Other += other.Other
+	s.Dropped += other.Dropped
+	s.Sampled += other.Sampled
+	s.Latency.Merge(other.Latency)
+	s.Response.Merge(other.Response)
+
+	limit := max(len(s.Exemplars), len(other.Exemplars))
+	for _, e := range other.Exemplars {
+		s.Exemplars = insertExemplar(s.Exemplars, e, limit)
+	}
+}
+
+// LatencySummary is the fixed set of percentiles written by sinks
+type LatencySummary struct {
+	P50  time.Duration `json:"p50_ns"`
+	P90  time.Duration `json:"p90_ns"`
+	P95  time.Duration `json:"p95_ns"`
+	P99  time.Duration `json:"p99_ns"`
+	P999 time.Duration `json:"p999_ns"`
+	Max  time.Duration `json:"max_ns"`
+	Mean time.Duration `json:"mean_ns"`
+}
+
+func (h *Histogram) Summary() LatencySummary {
+	return LatencySummary{
+		P50:  h.Quantile(0.5),
+		P90:  h.Quantile(0.9),
+		P95:  h.Quantile(0.95),
+		P99:  h.Quantile(0.99),
+		P999: h.Quantile(0.999),
+		Max:  h.Max(),
+		Mean: h.Mean(),
+	}
+}
+
+// quantiles yields OpenMetrics quantile label values with percentiles, max is quantile 1
+func (l LatencySummary) quantiles() iter.Seq2[string, time.Duration] {
+	return func(yield func(string, time.Duration) bool) {
+		for _, q := range []struct {
+			quantile string
+			value    time.Duration
+		}{
+			{"0.5", l.P50}, {"0.9", l.P90}, {"0.95", l.P95}, {"0.99", l.P99}, {"0.999", l.P999}, {"1", l.Max},
+		} {
+			if !yield(q.quantile, q.value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureSink keeps written windows in memory
+type captureSink struct {
+	windows []*Window
+}
+
+func (s *captureSink) Write(w *Window) error {
+	s.windows = append(s.windows, w)
+	return nil
+}
+
+func (s *captureSink) Close() error {
+	return nil
+}
+
+func TestNextWindowEnd(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, t0.Add(time.Second), nextWindowEnd(t0.Add(300*time.Millisecond), time.Second))
+	assert.Equal(t, t0.Add(200*time.Millisecond), nextWindowEnd(t0.Add(100*time.Millisecond), 100*time.Millisecond))
+	assert.Equal(t, t0.Add(time.Minute), nextWindowEnd(t0.Add(59*time.Second), time.Minute))
+}
+
+func TestWindowsAreAligned(t *testing.T) {
+	sink := &captureSink{}
+	o := New(nil, WithSinks(sink), WithWindow(time.Millisecond))
+	assert.Equal(t, minWindow, o.window)
+
+	o.StartLogging(context.Background())
+
+	deadline := time.Now().Add(350 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		o.StartSpan("UserDashboard").Done(nil)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	require.NoError(t, o.Close())
+	require.GreaterOrEqual(t, len(sink.windows), 3)
+
+	for i, w := range sink.windows {
+		if i > 0 {
+			assert.Equal(t, sink.windows[i-1].Time, w.Start, "windows follow each other")
+			assert.Zero(t, w.Start.UnixNano()%int64(minWindow), "window start is aligned")
+		}
+		if i < len(sink.windows)-1 {
+			assert.Zero(t, w.Time.UnixNano()%int64(minWindow), "window end is aligned")
+		}
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	dims := newDimensions(defaultAttrSetsLimit)
+
+	var steps []*Window
+	for i := range 3 {
+		ws := start.Add(time.Duration(i) * time.Second)
+		results := []result{{key: "UserDashboard", start: ws, duration: time.Duration(i+1) * time.Millisecond}}
+
+		steps = append(steps, newWindow(results, dims, nil, 0, ws, ws.Add(time.Second)))
+	}
+
+	summary := NewSummary()
+	for i := range steps {
+		w := slide(steps[max(i-1, 0) : i+1])
+		summary.Add(w)
+
+		if i == 2 {
+			assert.Equal(t, start.Add(time.Second), w.Start)
+			assert.Equal(t, start.Add(3*time.Second), w.Time)
+			require.Len(t, w.Spans, 1)
+			assert.Equal(t, int64(2), w.Spans[0].Count)
+			assert.Equal(t, 3*time.Millisecond, w.Spans[0].Latency.Max())
+		}
+	}
+
+	// overlapping windows are counted once
+	require.Len(t, summary.Keys, 1)
+	assert.Equal(t, int64(3), summary.Keys[0].Total.Count)
+	assert.Equal(t, 3*time.Second, summary.Duration())
+}
+
+func TestReadRunLogWindowLength(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.csv")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	sink := NewCSVSink(f)
+	start := time.Unix(1_700_000_000, 0)
+	for i := range 3 {
+		ws := start.Add(time.Duration(i) * 100 * time.Millisecond)
+		results := []result{{key: "UserDashboard", start: ws, duration: time.Millisecond}}
+
+		require.NoError(t, sink.Write(newWindow(results, newDimensions(defaultAttrSetsLimit), nil, 0, ws, ws.Add(100*time.Millisecond))))
+	}
+	require.NoError(t, sink.Close())
+
+	l, err := ReadRunLog(path)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{10, 10, 10}, l.Keys["UserDashboard"].RPS)
+}
+
+func TestReadRunLogSkipsOverlappingWindows(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Unix(1_700_000_000, 0)
+	dims := newDimensions(defaultAttrSetsLimit)
+
+	var steps []*Window
+	for i := range 6 {
+		ws := start.Add(time.Duration(i) * time.Second)
+		results := []result{{key: "UserDashboard", start: ws, duration: time.Millisecond}}
+
+		steps = append(steps, newWindow(results, dims, nil, 0, ws, ws.Add(time.Second)))
+	}
+
+	for _, name := range []string{"log.csv", "log.jsonl"} {
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		require.NoError(t, err)
+
+		var sink Sink = NewCSVSink(f)
+		if name == "log.jsonl" {
+			sink = NewJSONLSink(f)
+		}
+
+		for i := range steps {
+			require.NoError(t, sink.Write(slide(steps[max(i-1, 0):i+1])))
+		}
+		require.NoError(t, sink.Close())
+
+		l, err := ReadRunLog(path)
+		require.NoError(t, err)
+
+		// windows ending at 1s, 3s and 5s don't overlap, the rest share a step with them
+		assert.Equal(t, []float64{1, 1, 1}, l.Keys["UserDashboard"].RPS, name)
+		assert.Equal(t, int64(5), l.Keys["UserDashboard"].Count, name)
+	}
+}